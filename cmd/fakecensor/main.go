@@ -0,0 +1,21 @@
+// Поддельный сервис цензуры для локальной разработки без censorservice.
+package main
+
+import (
+	"commentservice/internal/censor/censortest"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func main() {
+	addr := flag.String("addr", ":5000", "address to listen on")
+	words := flag.String("words", "qwerty,йцукен,zxvbnm", "comma-separated list of banned words")
+	flag.Parse()
+
+	log.Printf("fake censor service listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, censortest.Handler(strings.Split(*words, ",")...)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,9 +1,17 @@
 package main
 
-import "commentservice/internal/app"
+import (
+	"commentservice/internal/app"
+	"os"
+)
 
 func main() {
-	err := app.Run()
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = app.Migrate(os.Args[2:])
+	} else {
+		err = app.Run()
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"commentservice/internal/health"
+	"commentservice/internal/models"
 	"commentservice/internal/service"
+	"commentservice/internal/stream"
+	transport "commentservice/internal/transport/http"
 	"context"
 	"fmt"
 	"net/http"
@@ -17,13 +21,53 @@ import (
 type Api struct {
 	r              *mux.Router
 	commentService service.CommentService
+	health         *health.Checker
+	metricsPath    string
+	metrics        http.Handler
+	graphql        http.Handler
+	playground     http.Handler
+	stream         *stream.Broker
+	heartbeat      time.Duration
+	allowedOrigin  func(origin string) bool
 }
 
-func NewApi(r *mux.Router, commentService service.CommentService) *Api {
+// Option настраивает Api
+type Option func(*Api)
+
+// WithHealthChecker задает проверки зависимостей для /readyz
+func WithHealthChecker(checker *health.Checker) Option {
+	return func(api *Api) {
+		api.health = checker
+	}
+}
+
+// WithMetrics публикует метрики по пути path основного сервера
+func WithMetrics(path string, handler http.Handler) Option {
+	return func(api *Api) {
+		api.metricsPath = path
+		api.metrics = handler
+	}
+}
+
+// WithGraphQL публикует GraphQL API на /graphql и, если playground не nil,
+// страницу playground на /graphql/playground
+func WithGraphQL(handler, playground http.Handler) Option {
+	return func(api *Api) {
+		api.graphql = handler
+		api.playground = playground
+	}
+}
+
+func NewApi(r *mux.Router, commentService service.CommentService, opts ...Option) *Api {
 	api := Api{
 		r:              mux.NewRouter(),
 		commentService: commentService,
+		health:         health.NewChecker(0),
 	}
+	for _, opt := range opts {
+		opt(&api)
+	}
+	api.r.Use(transport.RouteMiddleware)
 	api.endpoints()
 	return &api
 }
@@ -34,14 +78,57 @@ func (api *Api) Router() http.Handler {
 
 // Метод регистратор endpoint-ов
 func (api *Api) endpoints() {
+	api.v1Endpoints()
+
+	// Устаревшие маршруты с параметрами в строке запроса сохранены для
+	// совместимости с существующими клиентами
 	// маршрут предоставления списка комментариев по newsID
-	api.r.HandleFunc("/comments/?newsID=", api.getComments)
+	api.r.HandleFunc("/comments", legacy(api.getComments, "/v1/news/{newsID}/comments"))
+	api.r.HandleFunc("/comments/", legacy(api.getComments, "/v1/news/{newsID}/comments"))
 	// маршрут добавления комментария
-	api.r.HandleFunc("/addComment/?newsID=&comment=", api.addComment)
+	api.r.HandleFunc("/addComment", legacy(api.addComment, "/v1/news/{newsID}/comments"))
+	api.r.HandleFunc("/addComment/", legacy(api.addComment, "/v1/news/{newsID}/comments"))
+	// маршрут предоставления дерева комментариев по newsID
+	api.r.HandleFunc("/comments/thread", legacy(api.getCommentThread, "/v1/news/{newsID}/comments/thread"))
+	// маршрут редактирования комментария
+	api.r.HandleFunc("/updateComment", legacy(api.updateComment, "/v1/comments/{id}"))
+	// маршрут удаления комментария
+	api.r.HandleFunc("/deleteComment", legacy(api.deleteComment, "/v1/comments/{id}"))
+	// маршрут истории правок комментария
+	api.r.HandleFunc("/commentHistory", legacy(api.getCommentHistory, "/v1/comments/{id}/history"))
+	// пробы живости и готовности для оркестратора
+	api.r.HandleFunc("/healthz", api.healthz).Methods(http.MethodGet, http.MethodHead)
+	api.r.HandleFunc("/readyz", api.readyz).Methods(http.MethodGet, http.MethodHead)
+	if api.metrics != nil {
+		api.r.Handle(api.metricsPath, api.metrics).Methods(http.MethodGet)
+	}
+	if api.graphql != nil {
+		api.r.Handle("/graphql", api.graphql).Methods(http.MethodGet, http.MethodPost)
+	}
+	if api.playground != nil {
+		api.r.Handle("/graphql/playground", api.playground).Methods(http.MethodGet)
+	}
+
+	api.r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderError(w, r, "route not found", http.StatusNotFound)
+	})
+	api.r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderError(w, r, "method not allowed", http.StatusMethodNotAllowed)
+	})
+}
+
+// legacy помечает ответы устаревшего маршрута заголовками Deprecation и Link
+// с адресом маршрута /v1, который его заменяет
+func legacy(next http.HandlerFunc, successor string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		next(w, r)
+	}
 }
 
 func (api *Api) getComments(w http.ResponseWriter, r *http.Request) {
-	if !httputils.ValidateMethod(w, r, http.MethodGet, http.MethodOptions) {
+	if !validateMethod(w, r, http.MethodGet, http.MethodOptions) {
 		return
 	}
 
@@ -50,31 +137,53 @@ func (api *Api) getComments(w http.ResponseWriter, r *http.Request) {
 
 	params, err := parseURLParams(r.URL.String())
 	if err != nil {
-		httputils.RenderError(w, "failed to parse query parameters", http.StatusBadRequest, err)
+		renderError(w, r, "failed to parse query parameters", http.StatusBadRequest, err)
 		return
 	}
 	newsIDStr, exists := params["newsID"]
 	if !exists {
-		httputils.RenderError(w, "newsID parameter not found", http.StatusBadRequest)
+		renderError(w, r, "newsID parameter not found", http.StatusBadRequest)
 		return
 	}
 	newsID, err := strconv.Atoi(newsIDStr)
 	if err != nil {
-		httputils.RenderError(w, "failed to parse newsID", http.StatusBadRequest, err)
+		renderError(w, r, "failed to parse newsID", http.StatusBadRequest, err)
 		return
 	}
 
-	comments, err := api.commentService.GetComments(ctx, newsID)
+	// неизвестный sort отклоняется сервисом с кодом 400, как и в /v1
+	query := models.CommentQuery{
+		NewsID: newsID,
+		Cursor: params["cursor"],
+		Sort:   params["sort"],
+	}
+	// limit и offset необязательны: без них используется лимит по умолчанию
+	if limitStr, exists := params["limit"]; exists {
+		query.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || query.Limit < 0 {
+			renderError(w, r, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if offsetStr, exists := params["offset"]; exists {
+		query.Offset, err = strconv.Atoi(offsetStr)
+		if err != nil || query.Offset < 0 {
+			renderError(w, r, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := api.commentService.GetComments(ctx, query)
 	if err != nil {
-		httputils.RenderError(w, "failed to get comments from database", http.StatusInternalServerError, err)
+		renderServiceError(w, r, "failed to get comments", err)
 		return
 	}
 
-	httputils.RenderJSON(w, comments, http.StatusOK)
+	httputils.RenderJSON(w, page, http.StatusOK)
 }
 
 func (api *Api) addComment(w http.ResponseWriter, r *http.Request) {
-	if !httputils.ValidateMethod(w, r, http.MethodPost, http.MethodOptions) {
+	if !validateMethod(w, r, http.MethodPost, http.MethodOptions) {
 		return
 	}
 
@@ -83,38 +192,211 @@ func (api *Api) addComment(w http.ResponseWriter, r *http.Request) {
 
 	params, err := parseURLParams(r.URL.String())
 	if err != nil {
-		httputils.RenderError(w, "failed to parse query parameters from URL", http.StatusBadRequest, err)
+		renderError(w, r, "failed to parse query parameters from URL", http.StatusBadRequest, err)
 		return
 	}
 
 	newsIDStr, exists := params["newsID"]
 	if !exists {
-		httputils.RenderError(w, "newsID parameter not found", http.StatusBadRequest)
+		renderError(w, r, "newsID parameter not found", http.StatusBadRequest)
 		return
 	}
 	newsID, err := strconv.Atoi(newsIDStr)
 	if err != nil {
-		httputils.RenderError(w, "failed to parse newsID", http.StatusBadRequest, err)
+		renderError(w, r, "failed to parse newsID", http.StatusBadRequest, err)
 		return
 	}
 
 	comment, exists := params["comment"]
 	if !exists {
-		httputils.RenderError(w, "comment not found", http.StatusBadRequest)
+		renderError(w, r, "comment not found", http.StatusBadRequest)
+		return
+	}
+	if comment == "" {
+		renderError(w, r, "invalid comment", http.StatusBadRequest)
+		return
+	}
+
+	newComment := models.Comment{
+		NewsID:  newsID,
+		Content: comment,
+	}
+	if parentIDStr, exists := params["parentID"]; exists {
+		parentID, err := strconv.Atoi(parentIDStr)
+		if err != nil {
+			renderError(w, r, "failed to parse parentID", http.StatusBadRequest, err)
+			return
+		}
+		newComment.ParentID = &parentID
+	}
+
+	saved, err := api.commentService.AddComment(ctx, newComment)
+	if err != nil {
+		renderServiceError(w, r, "failed to add comment", err)
+		return
+	}
+
+	httputils.RenderJSON(w, saved, http.StatusCreated)
+
+}
+
+func (api *Api) getCommentThread(w http.ResponseWriter, r *http.Request) {
+	if !validateMethod(w, r, http.MethodGet, http.MethodOptions) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	params, err := parseURLParams(r.URL.String())
+	if err != nil {
+		renderError(w, r, "failed to parse query parameters", http.StatusBadRequest, err)
+		return
+	}
+	newsIDStr, exists := params["newsID"]
+	if !exists {
+		renderError(w, r, "newsID parameter not found", http.StatusBadRequest)
+		return
+	}
+	newsID, err := strconv.Atoi(newsIDStr)
+	if err != nil {
+		renderError(w, r, "failed to parse newsID", http.StatusBadRequest, err)
+		return
+	}
+
+	// depth необязателен: при отсутствии используется максимум из конфигурации.
+	// limit и offset выбирают страницу корневых комментариев, без них - все.
+	query := models.ThreadQuery{NewsID: newsID}
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"depth", &query.MaxDepth},
+		{"limit", &query.Limit},
+		{"offset", &query.Offset},
+	} {
+		str, exists := params[param.name]
+		if !exists {
+			continue
+		}
+		*param.value, err = strconv.Atoi(str)
+		if err != nil || *param.value < 0 {
+			renderError(w, r, "invalid "+param.name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	thread, total, err := api.commentService.GetCommentThread(ctx, query)
+	if err != nil {
+		renderServiceError(w, r, "failed to get comment thread", err)
+		return
+	}
+
+	// Тело устаревшего маршрута остается массивом, общее число корневых
+	// комментариев передается заголовком
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	httputils.RenderJSON(w, thread, http.StatusOK)
+}
+
+func (api *Api) updateComment(w http.ResponseWriter, r *http.Request) {
+	if !validateMethod(w, r, http.MethodPut, http.MethodPatch, http.MethodOptions) {
+		return
 	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	params, err := parseURLParams(r.URL.String())
+	if err != nil {
+		renderError(w, r, "failed to parse query parameters from URL", http.StatusBadRequest, err)
+		return
+	}
+	commentID, ok := parseCommentID(w, r, params)
+	if !ok {
+		return
+	}
+	comment := params["comment"]
 	if comment == "" {
-		httputils.RenderError(w, "invalid comment", http.StatusBadRequest)
+		renderError(w, r, "invalid comment", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := api.commentService.UpdateComment(ctx, commentID, comment)
+	if err != nil {
+		renderServiceError(w, r, "failed to update comment", err)
+		return
+	}
+
+	httputils.RenderJSON(w, updated, http.StatusOK)
+}
+
+func (api *Api) deleteComment(w http.ResponseWriter, r *http.Request) {
+	if !validateMethod(w, r, http.MethodDelete, http.MethodOptions) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	params, err := parseURLParams(r.URL.String())
+	if err != nil {
+		renderError(w, r, "failed to parse query parameters from URL", http.StatusBadRequest, err)
+		return
+	}
+	commentID, ok := parseCommentID(w, r, params)
+	if !ok {
+		return
+	}
+
+	if err := api.commentService.DeleteComment(ctx, commentID); err != nil {
+		renderServiceError(w, r, "failed to delete comment", err)
+		return
+	}
+
+	httputils.RenderJSON(w, "comment deleted successfully", http.StatusOK)
+}
+
+func (api *Api) getCommentHistory(w http.ResponseWriter, r *http.Request) {
+	if !validateMethod(w, r, http.MethodGet, http.MethodOptions) {
 		return
 	}
 
-	err = api.commentService.AddComment(ctx, newsID, comment)
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	params, err := parseURLParams(r.URL.String())
 	if err != nil {
-		httputils.RenderError(w, "failed to save comment to database", http.StatusInternalServerError, err)
+		renderError(w, r, "failed to parse query parameters", http.StatusBadRequest, err)
+		return
+	}
+	commentID, ok := parseCommentID(w, r, params)
+	if !ok {
 		return
 	}
 
-	httputils.RenderJSON(w, "comment saved successfully", http.StatusCreated)
+	revisions, err := api.commentService.GetCommentHistory(ctx, commentID)
+	if err != nil {
+		renderServiceError(w, r, "failed to get comment history", err)
+		return
+	}
+
+	httputils.RenderJSON(w, revisions, http.StatusOK)
+}
 
+// parseCommentID извлекает commentID из параметров запроса. При ошибке ответ
+// клиенту уже отправлен и возвращается false.
+func parseCommentID(w http.ResponseWriter, r *http.Request, params map[string]string) (int, bool) {
+	commentIDStr, exists := params["commentID"]
+	if !exists {
+		renderError(w, r, "commentID parameter not found", http.StatusBadRequest)
+		return 0, false
+	}
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		renderError(w, r, "failed to parse commentID", http.StatusBadRequest, err)
+		return 0, false
+	}
+	return commentID, true
 }
 
 func parseURLParams(input string) (map[string]string, error) {
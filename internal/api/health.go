@@ -0,0 +1,32 @@
+package api
+
+import (
+	"commentservice/internal/health"
+	"encoding/json"
+	"net/http"
+)
+
+// healthz проба живости: процесс запущен и обрабатывает запросы
+func (api *Api) healthz(w http.ResponseWriter, r *http.Request) {
+	renderHealth(w, map[string]string{"status": health.StatusOK}, http.StatusOK)
+}
+
+// readyz проба готовности: 503, если недоступна хотя бы одна критичная зависимость
+func (api *Api) readyz(w http.ResponseWriter, r *http.Request) {
+	report := api.health.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	renderHealth(w, report, status)
+}
+
+// renderHealth отправляет отчет без обертки JSONResponse, чтобы статус
+// ответа совпадал с полем status отчета
+func renderHealth(w http.ResponseWriter, body any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
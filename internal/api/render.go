@@ -0,0 +1,90 @@
+package api
+
+import (
+	"commentservice/internal/apperr"
+	transport "commentservice/internal/transport/http"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// renderError отправляет ошибку со статусом status. Тексты errs попадают в
+// ответ только для ошибок клиента, чтобы не раскрывать внутренние детали.
+func renderError(w http.ResponseWriter, r *http.Request, message string, status int, errs ...error) {
+	transport.WriteProblem(w, r, message, status, statusCode(status), errs...)
+}
+
+// renderServiceError отправляет ошибку сервиса, подбирая статус и код по ее категории
+func renderServiceError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	var rateLimit *apperr.RateLimitError
+	if errors.As(err, &rateLimit) && rateLimit.RetryAfter > 0 {
+		seconds := int(math.Ceil(rateLimit.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	status := errorStatus(err)
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	transport.WriteProblem(w, r, message, status, apperr.Code(err), err)
+}
+
+// errorStatus подбирает HTTP статус по категории ошибки
+func errorStatus(err error) int {
+	switch apperr.Code(err) {
+	case apperr.CodeNotFound:
+		return http.StatusNotFound
+	case apperr.CodeValidation:
+		return http.StatusBadRequest
+	case apperr.CodeCensored:
+		return http.StatusUnprocessableEntity
+	case apperr.CodeRateLimited:
+		return http.StatusTooManyRequests
+	case apperr.CodeConflict:
+		return http.StatusConflict
+	case apperr.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case apperr.CodeForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// statusCode подбирает код ошибки по HTTP статусу для ошибок, возникших в
+// самом api, например "unsupported_media_type" для 415
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return apperr.CodeValidation
+	case http.StatusNotFound:
+		return apperr.CodeNotFound
+	case http.StatusConflict:
+		return apperr.CodeConflict
+	case http.StatusUnauthorized:
+		return apperr.CodeUnauthenticated
+	case http.StatusForbidden:
+		return apperr.CodeForbidden
+	case http.StatusTooManyRequests:
+		return apperr.CodeRateLimited
+	case http.StatusInternalServerError:
+		return apperr.CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// validateMethod аналог httputils.ValidateMethod, отвечающий через renderError
+func validateMethod(w http.ResponseWriter, r *http.Request, allowedMethods ...string) bool {
+	if slices.Contains(allowedMethods, r.Method) {
+		return true
+	}
+	renderError(w, r,
+		fmt.Sprintf("Method %s is not allowed", r.Method),
+		http.StatusMethodNotAllowed,
+		fmt.Errorf("allowed methods: %v", allowedMethods),
+	)
+	return false
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"commentservice/internal/models"
+	"commentservice/internal/stream"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultHeartbeat период пустых сообщений, не дающих прокси закрыть соединение
+	defaultHeartbeat = 15 * time.Second
+	// wsWriteTimeout ограничивает запись одного сообщения WebSocket
+	wsWriteTimeout = 10 * time.Second
+	// wsReadLimit ограничивает размер сообщения от клиента; клиент ничего не
+	// присылает, кроме управляющих кадров
+	wsReadLimit = 512
+)
+
+// streamMessage событие в потоке: ID для Last-Event-ID и само событие
+type streamMessage struct {
+	ID uint64 `json:"id"`
+	models.CommentEvent
+}
+
+// WithStream включает потоковую выдачу событий комментариев новости через
+// SSE и WebSocket. heartbeat <= 0 означает период по умолчанию.
+func WithStream(broker *stream.Broker, heartbeat time.Duration) Option {
+	return func(api *Api) {
+		api.stream = broker
+		api.heartbeat = heartbeat
+		if api.heartbeat <= 0 {
+			api.heartbeat = defaultHeartbeat
+		}
+	}
+}
+
+// WithOriginCheck задает проверку Origin для WebSocket: на них не действует
+// CORS, поэтому браузерные подключения с чужих источников отклоняются здесь.
+// Без проверки разрешены только подключения со своего источника.
+func WithOriginCheck(allowed func(origin string) bool) Option {
+	return func(api *Api) {
+		api.allowedOrigin = allowed
+	}
+}
+
+// checkOrigin разрешает клиентов без Origin, свой источник и источники,
+// одобренные allowedOrigin
+func (api *Api) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return api.allowedOrigin != nil && api.allowedOrigin(origin)
+}
+
+// v1StreamSSE GET /v1/news/{newsID}/comments/stream - события в формате
+// Server-Sent Events. Пропущенные события досылаются по заголовку Last-Event-ID.
+func (api *Api) v1StreamSSE(w http.ResponseWriter, r *http.Request) {
+	newsID, ok := pathID(w, r, "newsID")
+	if !ok {
+		return
+	}
+
+	// Поток живет дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		renderError(w, r, "streaming is not supported", http.StatusInternalServerError, err)
+		return
+	}
+
+	sub, missed := api.stream.Subscribe(newsID, lastEventID(r))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		if err := writeSSE(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(api.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Подписка закрыта; клиент переподключится с Last-Event-ID
+				return
+			}
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE записывает событие в формате text/event-stream
+func writeSSE(w http.ResponseWriter, event stream.Event) error {
+	data, err := json.Marshal(event.CommentEvent)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// v1StreamWS GET /v1/news/{newsID}/comments/ws - события через WebSocket.
+// Пропущенные события досылаются по параметру last_event_id.
+func (api *Api) v1StreamWS(w http.ResponseWriter, r *http.Request) {
+	newsID, ok := pathID(w, r, "newsID")
+	if !ok {
+		return
+	}
+
+	// Upgrade сам отвечает клиенту при ошибке
+	upgrader := websocket.Upgrader{CheckOrigin: api.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub, missed := api.stream.Subscribe(newsID, lastEventID(r))
+	defer sub.Close()
+
+	// Чтение нужно, чтобы обрабатывать pong и закрытие соединения клиентом
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(wsReadLimit)
+		conn.SetReadDeadline(time.Now().Add(2 * api.heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * api.heartbeat))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range missed {
+		if err := writeWS(conn, event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(api.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				code, reason := websocket.CloseGoingAway, "server is shutting down"
+				if sub.Lagged() {
+					code, reason = websocket.CloseTryAgainLater, "subscriber is too slow"
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err := writeWS(conn, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeWS отправляет событие текстовым сообщением JSON
+func writeWS(conn *websocket.Conn, event stream.Event) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(streamMessage{ID: event.ID, CommentEvent: event.CommentEvent})
+}
+
+// lastEventID возвращает ID последнего полученного клиентом события из
+// заголовка Last-Event-ID или параметра last_event_id; 0, если его нет
+func lastEventID(r *http.Request) uint64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"commentservice/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httputils "github.com/Fau1con/renderresponse"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// requestTimeout ограничивает обработку одного запроса
+	requestTimeout = 10 * time.Second
+	// maxBodySize максимальный размер тела запроса
+	maxBodySize = 64 << 10
+	// totalCountHeader передает общее число корневых комментариев в устаревшем маршруте ветки
+	totalCountHeader = "X-Total-Count"
+)
+
+// addCommentBody тело POST /v1/news/{newsID}/comments
+type addCommentBody struct {
+	Content  string `json:"content"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+// updateCommentBody тело PATCH /v1/comments/{id}
+type updateCommentBody struct {
+	Content string `json:"content"`
+}
+
+// threadResponse ответ GET /v1/news/{newsID}/comments/thread: деревья
+// страницы корневых комментариев и общее число корневых комментариев
+type threadResponse struct {
+	Nodes []*models.CommentNode `json:"nodes"`
+	Total int                   `json:"total"`
+}
+
+// reactionBody тело PUT /v1/comments/{id}/reaction
+type reactionBody struct {
+	Reaction string `json:"reaction"`
+}
+
+// v1Endpoints регистрирует ресурсные маршруты API версии 1
+func (api *Api) v1Endpoints() {
+	api.r.HandleFunc("/v1/news/{newsID:[0-9]+}/comments", api.v1ListComments).Methods(http.MethodGet)
+	api.r.HandleFunc("/v1/news/{newsID:[0-9]+}/comments", api.v1AddComment).Methods(http.MethodPost)
+	api.r.HandleFunc("/v1/news/{newsID:[0-9]+}/comments/thread", api.v1CommentThread).Methods(http.MethodGet)
+	if api.stream != nil {
+		api.r.HandleFunc("/v1/news/{newsID:[0-9]+}/comments/stream", api.v1StreamSSE).Methods(http.MethodGet)
+		api.r.HandleFunc("/v1/news/{newsID:[0-9]+}/comments/ws", api.v1StreamWS).Methods(http.MethodGet)
+	}
+
+	api.r.HandleFunc("/v1/comments/{id:[0-9]+}", api.v1GetComment).Methods(http.MethodGet)
+	api.r.HandleFunc("/v1/comments/{id:[0-9]+}", api.v1UpdateComment).Methods(http.MethodPatch)
+	api.r.HandleFunc("/v1/comments/{id:[0-9]+}", api.v1DeleteComment).Methods(http.MethodDelete)
+	api.r.HandleFunc("/v1/comments/{id:[0-9]+}/history", api.v1CommentHistory).Methods(http.MethodGet)
+	api.r.HandleFunc("/v1/comments/{id:[0-9]+}/reaction", api.v1SetReaction).Methods(http.MethodPut)
+	api.r.HandleFunc("/v1/comments/{id:[0-9]+}/reaction", api.v1RemoveReaction).Methods(http.MethodDelete)
+}
+
+// v1ListComments GET /v1/news/{newsID}/comments?limit=&offset=&cursor=&sort=&reactions=
+func (api *Api) v1ListComments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	newsID, ok := pathID(w, r, "newsID")
+	if !ok {
+		return
+	}
+	query := models.CommentQuery{
+		NewsID: newsID,
+		Cursor: r.URL.Query().Get("cursor"),
+		Sort:   r.URL.Query().Get("sort"),
+	}
+	var err error
+	if query.Limit, err = queryInt(r, "limit"); err != nil {
+		renderError(w, r, "invalid limit", http.StatusBadRequest, err)
+		return
+	}
+	if query.Offset, err = queryInt(r, "offset"); err != nil {
+		renderError(w, r, "invalid offset", http.StatusBadRequest, err)
+		return
+	}
+	if query.WithReactions, err = queryBool(r, "reactions"); err != nil {
+		renderError(w, r, "invalid reactions", http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := api.commentService.GetComments(ctx, query)
+	if err != nil {
+		renderServiceError(w, r, "failed to get comments", err)
+		return
+	}
+
+	httputils.RenderJSON(w, page, http.StatusOK)
+}
+
+// v1AddComment POST /v1/news/{newsID}/comments
+func (api *Api) v1AddComment(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	newsID, ok := pathID(w, r, "newsID")
+	if !ok {
+		return
+	}
+	var body addCommentBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		renderError(w, r, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := api.commentService.AddComment(ctx, models.Comment{
+		NewsID:   newsID,
+		ParentID: body.ParentID,
+		Content:  body.Content,
+	})
+	if err != nil {
+		renderServiceError(w, r, "failed to add comment", err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/comments/%d", saved.CommentID))
+	httputils.RenderJSON(w, saved, http.StatusCreated)
+}
+
+// v1CommentThread GET /v1/news/{newsID}/comments/thread?depth=&limit=&offset=
+// limit и offset выбирают страницу корневых комментариев
+func (api *Api) v1CommentThread(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	newsID, ok := pathID(w, r, "newsID")
+	if !ok {
+		return
+	}
+	query := models.ThreadQuery{NewsID: newsID}
+	var err error
+	if query.MaxDepth, err = queryInt(r, "depth"); err != nil {
+		renderError(w, r, "invalid depth", http.StatusBadRequest, err)
+		return
+	}
+	if query.Limit, err = queryInt(r, "limit"); err != nil {
+		renderError(w, r, "invalid limit", http.StatusBadRequest, err)
+		return
+	}
+	if query.Offset, err = queryInt(r, "offset"); err != nil {
+		renderError(w, r, "invalid offset", http.StatusBadRequest, err)
+		return
+	}
+
+	nodes, total, err := api.commentService.GetCommentThread(ctx, query)
+	if err != nil {
+		renderServiceError(w, r, "failed to get comment thread", err)
+		return
+	}
+
+	httputils.RenderJSON(w, threadResponse{Nodes: nodes, Total: total}, http.StatusOK)
+}
+
+// v1GetComment GET /v1/comments/{id}
+func (api *Api) v1GetComment(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	commentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	comment, err := api.commentService.GetComment(ctx, commentID)
+	if err != nil {
+		renderServiceError(w, r, "failed to get comment", err)
+		return
+	}
+
+	httputils.RenderJSON(w, comment, http.StatusOK)
+}
+
+// v1UpdateComment PATCH /v1/comments/{id}
+func (api *Api) v1UpdateComment(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	commentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body updateCommentBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		renderError(w, r, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := api.commentService.UpdateComment(ctx, commentID, body.Content)
+	if err != nil {
+		renderServiceError(w, r, "failed to update comment", err)
+		return
+	}
+
+	httputils.RenderJSON(w, updated, http.StatusOK)
+}
+
+// v1DeleteComment DELETE /v1/comments/{id}
+func (api *Api) v1DeleteComment(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	commentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := api.commentService.DeleteComment(ctx, commentID); err != nil {
+		renderServiceError(w, r, "failed to delete comment", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v1CommentHistory GET /v1/comments/{id}/history
+func (api *Api) v1CommentHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	commentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	revisions, err := api.commentService.GetCommentHistory(ctx, commentID)
+	if err != nil {
+		renderServiceError(w, r, "failed to get comment history", err)
+		return
+	}
+
+	httputils.RenderJSON(w, revisions, http.StatusOK)
+}
+
+// v1SetReaction PUT /v1/comments/{id}/reaction - ставит или меняет реакцию
+// пользователя и возвращает сводку реакций
+func (api *Api) v1SetReaction(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	commentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var body reactionBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	summary, err := api.commentService.SetReaction(ctx, commentID, body.Reaction)
+	if err != nil {
+		renderServiceError(w, r, "failed to set reaction", err)
+		return
+	}
+
+	httputils.RenderJSON(w, summary, http.StatusOK)
+}
+
+// v1RemoveReaction DELETE /v1/comments/{id}/reaction - снимает реакцию
+// пользователя и возвращает сводку реакций
+func (api *Api) v1RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	commentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	summary, err := api.commentService.RemoveReaction(ctx, commentID)
+	if err != nil {
+		renderServiceError(w, r, "failed to remove reaction", err)
+		return
+	}
+
+	httputils.RenderJSON(w, summary, http.StatusOK)
+}
+
+// pathID извлекает числовой параметр пути. При ошибке ответ клиенту уже
+// отправлен и возвращается false.
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil || id <= 0 {
+		renderError(w, r, fmt.Sprintf("invalid %s", name), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// queryInt разбирает необязательный неотрицательный параметр запроса; 0, если его нет
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", name)
+	}
+	return n, nil
+}
+
+// queryBool разбирает необязательный логический параметр запроса; false, если его нет
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// decodeJSON проверяет Content-Type и разбирает тело запроса в v. Неизвестные
+// поля и лишние данные после объекта считаются ошибкой. При ошибке ответ
+// клиенту уже отправлен и возвращается false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		renderError(w, r, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			renderError(w, r, "request body is too large", http.StatusRequestEntityTooLarge, err)
+			return false
+		}
+		renderError(w, r, "failed to decode request body", http.StatusBadRequest, err)
+		return false
+	}
+	if decoder.More() {
+		renderError(w, r, "request body must contain a single JSON object", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// newAdminServer создает административный сервер на отдельном порту.
+// Возвращает nil, если порт не задан.
+func newAdminServer(cfg *config.Config, router *mux.Router) *http.Server {
+	if cfg.GetAdminPort() <= 0 {
+		return nil
+	}
+	return &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.GetAdminPort()),
+		Handler:      router,
+		ReadTimeout:  cfg.GetReadTimeout(),
+		WriteTimeout: cfg.GetWriteTimeout(),
+	}
+}
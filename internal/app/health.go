@@ -0,0 +1,90 @@
+package app
+
+import (
+	"commentservice/internal/health"
+	"commentservice/internal/infrastructure/config"
+	kafkatransport "commentservice/internal/transport/kafka"
+	"commentservice/storage"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// pinger зависимость, умеющая проверить свою доступность
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// consumerCheck входной топик воркера для проверки готовности
+type consumerCheck struct {
+	worker *kafkatransport.Worker
+	topic  string
+}
+
+// readinessChecks собирает проверки зависимостей для /readyz
+type readinessChecks struct {
+	cfg     *config.Config
+	checker *health.Checker
+	client  *http.Client
+}
+
+func newReadinessChecks(cfg *config.Config) *readinessChecks {
+	return &readinessChecks{
+		cfg:     cfg,
+		checker: health.NewChecker(cfg.GetHealthCheckTimeout()),
+		client:  &http.Client{},
+	}
+}
+
+// register добавляет проверку, учитывая список некритичных зависимостей
+func (rc *readinessChecks) register(name string, check health.CheckFunc) {
+	var opts []health.Option
+	if rc.cfg.IsNonCritical(name) {
+		opts = append(opts, health.NonCritical())
+	}
+	rc.checker.Register(name, check, opts...)
+}
+
+// addStorages проверяет пулы postgres; хранилища в памяти всегда доступны,
+// а API newsservice проверяется по маршруту
+func (rc *readinessChecks) addStorages(comments storage.CommentsStorage, news storage.NewsStorage) error {
+	if p, ok := comments.(pinger); ok {
+		rc.register("comments_db", p.Ping)
+	}
+	switch rc.cfg.GetNewsStorageDriver() {
+	case config.StorageDriverHTTP:
+		routeName := rc.cfg.Storage.NewsAPI.Route
+		if routeName == "" {
+			routeName = defaultNewsRoute
+		}
+		return rc.addRoute("news_api", routeName)
+	default:
+		if p, ok := news.(pinger); ok {
+			rc.register("news_db", p.Ping)
+		}
+	}
+	return nil
+}
+
+// addRoute проверяет доступность внешнего сервиса по маршруту из конфигурации
+func (rc *readinessChecks) addRoute(name, routeName string) error {
+	route, err := rc.cfg.GetRoute(routeName)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(route.BaseURL, "/") + route.HealthPath
+	rc.register(name, health.HTTPCheck(rc.client, url))
+	return nil
+}
+
+// addKafka проверяет брокеры продюсера и для каждого воркера - что он
+// запущен и его входной топик существует
+func (rc *readinessChecks) addKafka(producer *kafkatransport.Producer, brokers []string, consumers ...consumerCheck) {
+	rc.register("kafka_producer", producer.Ping)
+	for _, c := range consumers {
+		rc.register("kafka_consumer_"+c.worker.Name(), func(ctx context.Context) error {
+			return errors.Join(c.worker.Ping(ctx), kafkatransport.PingTopic(ctx, brokers, c.topic))
+		})
+	}
+}
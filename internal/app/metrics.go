@@ -0,0 +1,19 @@
+package app
+
+import (
+	"commentservice/internal/metrics"
+	"commentservice/storage"
+)
+
+// instrumentStorages подключает метрики к хранилищам postgres: длительность
+// методов и статистику пулов соединений
+func instrumentStorages(m *metrics.Metrics, storages ...any) {
+	for _, s := range storages {
+		pg, ok := s.(*storage.Storage)
+		if !ok {
+			continue
+		}
+		pg.SetQueryObserver(m)
+		m.RegisterPool(pg.Name(), pg)
+	}
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"commentservice/internal/logging"
+	"commentservice/storage"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: migrate up | down [N] | status"
+
+// Migrate выполняет команду migrate над базой комментариев:
+// up применяет все новые миграции, down [N] откатывает N последних
+// (по умолчанию одну), status выводит состояние миграций.
+func Migrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config from config file: %w", err)
+	}
+	if cfg.GetStorageDriver() != config.StorageDriverPostgres {
+		return fmt.Errorf("migrations require %s storage driver", config.StorageDriverPostgres)
+	}
+	log, _, logOutput, err := logging.New(cfg.Logging)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer logOutput.Close()
+
+	commentStorage, err := storage.NewCommentStorage(cfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to connect to comments database: %w", err)
+	}
+	defer commentStorage.Close()
+
+	migrator, err := storage.NewMigrator(commentStorage)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", n)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.AppliedAt != nil {
+				appliedAt = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%03d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, %s", args[0], migrateUsage)
+	}
+
+	return nil
+}
+
+// autoMigrate применяет новые миграции при запуске сервиса
+func autoMigrate(ctx context.Context, commentStorage *storage.Storage) error {
+	migrator, err := storage.NewMigrator(commentStorage)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
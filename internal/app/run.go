@@ -2,170 +2,337 @@ package app
 
 import (
 	"commentservice/internal/api"
+	"commentservice/internal/auth"
+	"commentservice/internal/censor"
+	"commentservice/internal/clientip"
+	"commentservice/internal/graph"
 	"commentservice/internal/infrastructure/config"
+	"commentservice/internal/logging"
+	"commentservice/internal/metrics"
+	"commentservice/internal/moderation"
+	"commentservice/internal/outbox"
+	"commentservice/internal/ratelimit"
 	"commentservice/internal/service"
+	"commentservice/internal/stream"
+	"commentservice/internal/tracing"
 	transport "commentservice/internal/transport/http"
+	kafkatransport "commentservice/internal/transport/kafka"
 	"commentservice/storage"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
-	"strings"
-	"time"
+	"syscall"
 
-	kfk "github.com/Fau1con/kafkawrapper"
 	"github.com/gorilla/mux"
 )
 
-// Run запускает Commentservice приложение
+// configPath путь к файлу конфигурации
+const configPath = "configs/dev.yaml"
+
+// Run запускает Commentservice приложение и работает до сигнала остановки.
+// При остановке сначала дорабатывают HTTP запросы, затем останавливаются
+// воркеры (фиксируя смещения обработанных сообщений), после чего
+// закрываются продюсер и пулы хранилищ.
 func Run() error {
-	ctxMain, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctxMain, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	cfg, err := config.LoadConfig("configs/dev.yaml")
+	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		log.Println("failed to load config from config file")
 		return fmt.Errorf("failed to load config from config file: %w", err)
 	}
-	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug, // cfg.Logging.Level
-	}))
+	log, logLevel, logOutput, err := logging.New(cfg.Logging)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer logOutput.Close()
+	slog.SetDefault(log)
 
-	commentStorage, err := storage.NewCommentStorage(cfg, log)
+	shutdownTracing, err := tracing.Setup(ctxMain, cfg.Tracing, cfg.GetAppName())
 	if err != nil {
-		return fmt.Errorf("failed to connect to comments database: %w", err)
+		return fmt.Errorf("failed to set up tracing: %w", err)
 	}
-	defer commentStorage.Close()
+	// Спаны выгружаются последними, после закрытия остальных зависимостей
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.GetShutdownTimeout())
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("failed to flush traces", "error", err)
+		}
+	}()
 
-	newsStorage, err := storage.NewNewsStorage(cfg, log)
+	commentStorage, newsStorage, err := openStorages(ctxMain, cfg, log)
 	if err != nil {
-		return fmt.Errorf("failed to connect to news database: %w", err)
+		return err
 	}
+	defer commentStorage.Close()
 	defer newsStorage.Close()
 
-	commentService := service.NewCommentService(commentStorage, newsStorage, log)
+	workers := newSupervisor(log)
+	readiness := newReadinessChecks(cfg)
+	if err := readiness.addStorages(commentStorage, newsStorage); err != nil {
+		return fmt.Errorf("failed to configure readiness checks: %w", err)
+	}
 
-	apiInstance := api.NewApi(mux.NewRouter(), commentService)
+	serviceOpts := []service.Option{
+		service.WithMaxThreadDepth(cfg.GetMaxThreadDepth()),
+		service.WithDefaultLimit(cfg.GetDefaultCommentLimit()),
+	}
 
-	// port := fmt.Sprintf("%d", cfg.GetPort())
-	// addr := "localhost:" + port
+	// Административные маршруты отдаются на отдельном порту, если он задан,
+	// иначе - на основном
+	adminRouter := mux.NewRouter()
+	adminServer := newAdminServer(cfg, adminRouter)
+	var apiOpts []api.Option
+	var requestObservers []transport.RequestObserver
 
-	kafkaBrokers := cfg.Kafka.Brokers
-	if len(kafkaBrokers) == 0 {
-		kafkaBrokers[0] = "kafka:9093"
+	var appMetrics *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.New()
+		instrumentStorages(appMetrics, commentStorage, newsStorage)
+		serviceOpts = append(serviceOpts, service.WithMetrics(appMetrics))
+		requestObservers = append(requestObservers, appMetrics)
+		if adminServer != nil {
+			adminRouter.Handle(cfg.GetMetricsPath(), appMetrics.Handler()).Methods(http.MethodGet)
+		} else {
+			apiOpts = append(apiOpts, api.WithMetrics(cfg.GetMetricsPath(), appMetrics.Handler()))
+		}
 	}
-	consumer, err := kfk.NewConsumer(kafkaBrokers, "news_input")
-	if err != nil {
-		log.Error("failed to create Kafka consumer: ",
-			slog.Any("%v\n", kafkaBrokers))
-		return err
+	// Уровень логирования меняется только через административный порт
+	if adminServer != nil {
+		adminRouter.Handle("/loglevel", logging.LevelHandler(logLevel, log))
+	} else {
+		log.Warn("admin port is not configured, runtime log level changes are disabled")
+	}
+	if cfg.Moderation.Enabled {
+		filter, err := moderation.NewFilter(cfg.Moderation, log)
+		if err != nil {
+			return fmt.Errorf("failed to load moderation dictionaries: %w", err)
+		}
+		if interval := cfg.GetModerationReloadInterval(); interval > 0 {
+			workers.add("moderation_reload", func(ctx context.Context) error {
+				return filter.Watch(ctx, interval)
+			})
+		}
+		serviceOpts = append(serviceOpts, service.WithFilter(filter))
 	}
-	producer, err := kfk.NewProducer(kafkaBrokers)
-	log.Info("producer created! Broker: ",
-		slog.Any("%v\n", kafkaBrokers))
+	if cfg.Censor.Enabled {
+		censorOpt, err := newCensorOption(cfg, log)
+		if err != nil {
+			return fmt.Errorf("failed to create censor client: %w", err)
+		}
+		serviceOpts = append(serviceOpts, censorOpt)
+		if err := readiness.addRoute("censor", censorRouteName(cfg)); err != nil {
+			return fmt.Errorf("failed to configure readiness checks: %w", err)
+		}
+	}
+
+	// Без включенной аутентификации авторство и права на изменение не проверяются
+	var verifier *auth.Verifier
+	if cfg.Auth.Enabled {
+		var err error
+		verifier, err = auth.NewVerifier(cfg.Auth)
+		if err != nil {
+			return fmt.Errorf("failed to create token verifier: %w", err)
+		}
+		serviceOpts = append(serviceOpts, service.WithAuthorization(cfg.GetModeratorRole()))
+	}
+
+	trustedProxies, err := clientip.ParseProxies(cfg.RateLimit.TrustedProxies)
 	if err != nil {
-		log.Error("failed to create Kafka poducer",
-			slog.Any("%v\n", err))
-		return err
+		return fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.NewMemoryLimiter()
+		workers.add("rate_limit_sweep", limiter.Run)
+		serviceOpts = append(serviceOpts, service.WithRateLimit(limiter, ratelimit.NewLimits(cfg.RateLimit)))
 	}
 
-	// Горутина для обработки Kafka сообщений
+	// События для потоковой выдачи приходят либо от записей этой реплики,
+	// либо из топика событий, общего для всех реплик
+	var broker *stream.Broker
+	if cfg.Stream.Enabled {
+		broker = stream.NewBroker(cfg.Stream.History, cfg.Stream.BufferSize)
+		workers.add("stream_sweep", broker.Run)
+		if cfg.GetStreamFanout() == config.StreamFanoutLocal {
+			serviceOpts = append(serviceOpts, service.WithEventPublisher(broker))
+		}
+	}
 
-	go func() {
-		for {
-			log.Info("start getting message and redirecting")
-			msg, err := consumer.GetMessages(ctxMain)
-			if err != nil {
-				log.Error("failed to read message from Kafka",
-					slog.Any("%v\n", err))
-				continue
-			}
+	commentService := service.NewCommentService(commentStorage, newsStorage, log, serviceOpts...)
 
-			path := string(msg.Value)
-			if strings.TrimSpace(path) == "" {
-				log.Error("failed to read data from Kafka message",
-					slog.Any("error", fmt.Errorf("path is empty")))
-				continue
-			}
+	kafkaBrokers := cfg.Kafka.Brokers
+	if len(kafkaBrokers) == 0 {
+		kafkaBrokers = []string{"kafka:9093"}
+	}
+	producer := kafkatransport.NewProducer(kafkaBrokers)
+	defer producer.Close()
+	if appMetrics != nil {
+		producer.SetObserver(appMetrics)
+	}
+	log.Info("Kafka producer created", "brokers", kafkaBrokers)
 
-			data, err := sendRequestToLocalhost(string(msg.Value))
-			if err != nil {
-				log.Error("failed to read data from Kafka message",
-					slog.Any("%v\n", err))
-				continue
-			}
+	// Обработчики запросов, приходящих через Kafka
+	kafkaHandlers := kafkatransport.NewHandlers(commentService, log)
+	listWorker := kafkatransport.NewWorker("list_comments",
+		kafkatransport.NewConsumerFactory(kafkaBrokers, cfg.GetCommentInputTopic(), cfg.GetConsumerGroup("list_comments")),
+		producer, cfg.GetCommentsTopic(), kafkaHandlers.ListComments, log)
+	addWorker := kafkatransport.NewWorker("add_comment",
+		kafkatransport.NewConsumerFactory(kafkaBrokers, cfg.GetAddCommentInputTopic(), cfg.GetConsumerGroup("add_comment")),
+		producer, cfg.GetAddCommentTopic(), kafkaHandlers.AddComment, log)
+	if appMetrics != nil {
+		listWorker.SetObserver(appMetrics)
+		addWorker.SetObserver(appMetrics)
+	}
+	if cfg.Auth.Enabled && len(cfg.Auth.APIKeys) > 0 {
+		apiKeys := auth.NewAPIKeys(cfg.Auth.APIKeys)
+		listWorker.SetAuthenticator(apiKeys)
+		addWorker.SetAuthenticator(apiKeys)
+	}
+	workers.add(listWorker.Name(), listWorker.Run)
+	workers.add(addWorker.Name(), addWorker.Run)
+	readiness.addKafka(producer, kafkaBrokers,
+		consumerCheck{worker: listWorker, topic: cfg.GetCommentInputTopic()},
+		consumerCheck{worker: addWorker, topic: cfg.GetAddCommentInputTopic()})
 
-			if strings.Contains(string(msg.Value), "/comments/?newsID=") {
-				err := producer.SendMessage(ctxMain, cfg.Kafka.Topics.Comments, data)
-				if err != nil {
-					log.Error("failed to write messageto Kafka",
-						slog.Any("%v\n", err))
-					return
-				}
-			}
-			if strings.Contains(string(msg.Value), "/addcomment/?newsID=&comment=") {
-				err := producer.SendMessage(ctxMain, cfg.Kafka.Topics.AddComment, data)
-				if err != nil {
-					log.Error("failed to write message to Kafka",
-						slog.Any("%v\n", err))
-					return
-				}
-			}
+	if broker != nil && cfg.GetStreamFanout() == config.StreamFanoutKafka {
+		fanout := kafkatransport.NewFanout(
+			kafkatransport.NewFanoutConsumerFactory(kafkaBrokers, cfg.GetCommentEventsTopic(), cfg.GetStreamConsumerGroup()),
+			broker, log)
+		workers.add("stream_fanout", fanout.Run)
+	}
+
+	if outboxStorage, ok := commentStorage.(storage.OutboxStorage); ok && cfg.Outbox.Enabled {
+		relay := outbox.NewRelay(outboxStorage, producer, cfg.GetCommentEventsTopic(),
+			cfg.Outbox.BatchSize, cfg.GetOutboxPollInterval(), cfg.GetOutboxRetention(), log)
+		workers.add("outbox_relay", relay.Run)
+	}
+
+	if cfg.GraphQL.Enabled {
+		var playground http.Handler
+		if cfg.GraphQL.Playground {
+			playground = graph.PlaygroundHandler("/graphql")
 		}
-	}()
+		apiOpts = append(apiOpts, api.WithGraphQL(graph.NewHandler(commentService, cfg), playground))
+	}
+	if broker != nil {
+		apiOpts = append(apiOpts, api.WithStream(broker, cfg.GetStreamHeartbeat()))
+	}
+	cors := transport.NewCORS(cfg.CORS)
+	apiOpts = append(apiOpts, api.WithOriginCheck(cors.AllowedOrigin))
+	apiOpts = append(apiOpts, api.WithHealthChecker(readiness.checker))
+	apiInstance := api.NewApi(mux.NewRouter(), commentService, apiOpts...)
 
 	var handler http.Handler = apiInstance.Router()
-	handler = transport.CORSMiddleware()(handler)
+	if verifier != nil {
+		handler = transport.AuthMiddleware(verifier, log)(handler)
+	}
+	handler = cors.Middleware(handler)
+	handler = transport.ClientIPMiddleware(cfg.RateLimit.TrustForwardedFor, trustedProxies)(handler)
+	handler = transport.TracingMiddleware(handler)
+	handler = transport.LoggingMiddleware(log, requestObservers...)(handler)
 	handler = transport.RequestIDMiddleware(handler)
-	handler = transport.LoggingMiddleware(log)(handler)
 
-	log.Info("server commentservice APP start working at port",
-		slog.Any("%v\n", cfg.HTTP.Port))
-	return http.ListenAndServe(":"+strconv.Itoa(cfg.HTTP.Port), handler)
+	server := &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.GetPort()),
+		Handler:      handler,
+		ReadTimeout:  cfg.GetReadTimeout(),
+		WriteTimeout: cfg.GetWriteTimeout(),
+	}
+	// Потоковые ответы не дают серверу остановиться, пока открыты
+	if broker != nil {
+		server.RegisterOnShutdown(broker.Close)
+	}
 
-	// quit := make(chan os.Signal, 1)
-	// signal.Notify(quit, os.Interrupt.syscall.SIGTERM)
-	// <-quit
-	// log.Info("Shutdown server...")
-	// ctxShutdown, cancelSutdown := context.WithTimeout(context.Background(), 30*time.Second)
-	// defer cancelSutdown()
+	// Воркеры живут в собственном контексте, чтобы не останавливаться
+	// раньше, чем завершатся HTTP запросы, которые могут от них зависеть
+	ctxWorkers, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+	workers.start(ctxWorkers)
 
-	// return server.Shutdown(ctxShutdown)
-}
+	serverErr := make(chan error, 2)
+	go func() {
+		log.Info("server commentservice APP start working at port", "port", cfg.GetPort())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	if adminServer != nil {
+		go func() {
+			log.Info("admin server start working at port", "port", cfg.GetAdminPort())
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("admin server: %w", err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctxMain.Done():
+		log.Info("shutdown signal received")
+	case runErr = <-serverErr:
+		log.Error("http server failed", "error", runErr)
+	}
+	// Повторный сигнал завершает процесс сразу
+	stop()
+
+	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), cfg.GetShutdownTimeout())
+	defer cancelShutdown()
+
+	log.Info("Shutdown server...", "timeout", cfg.GetShutdownTimeout())
+	if err := server.Shutdown(ctxShutdown); err != nil {
+		log.Error("failed to drain HTTP requests", "error", err)
+		runErr = errors.Join(runErr, fmt.Errorf("http server shutdown: %w", err))
+	}
+
+	cancelWorkers()
+	if err := workers.wait(ctxShutdown); err != nil {
+		log.Error("failed to stop workers", "error", err)
+		runErr = errors.Join(runErr, err)
+	}
 
-// sendRequestToLocalhost выполняет HTTP запрос к локальному сервису
-func sendRequestToLocalhost(path string) ([]byte, error) {
-	if path == "" {
-		return nil, fmt.Errorf("path cannot be empty")
+	// Административный сервер останавливается последним, чтобы метрики
+	// были доступны, пока идет остановка
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctxShutdown); err != nil {
+			runErr = errors.Join(runErr, fmt.Errorf("admin server shutdown: %w", err))
+		}
 	}
 
-	url := fmt.Sprintf("http://localhost:8081%s", path)
-	req, err := http.NewRequest(http.MethodGet, url, nil) // TODO: Impl Method POST
+	log.Info("server commentservice APP stopped")
+	return runErr
+}
+
+// newCensorOption создает клиент сервиса цензуры по настройкам из конфигурации
+func newCensorOption(cfg *config.Config, log *slog.Logger) (service.Option, error) {
+	route, err := cfg.GetRoute(censorRouteName(cfg))
 	if err != nil {
-		log.Printf("failed to create request: %v\n", err)
 		return nil, err
 	}
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Do(req)
+	policy, err := censor.ParsePolicy(cfg.Censor.Policy)
 	if err != nil {
-		log.Printf("failed to send request: %v\n", err)
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	client, err := censor.NewClient(route, cfg.Censor, log)
 	if err != nil {
-		log.Printf("failed to read response: %v\n", err)
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("unexpected response code: %d\n", resp.StatusCode)
+
+	log.Info("censor enabled", "route", route.BaseURL, "policy", policy)
+	return service.WithCensor(client, policy, cfg.Censor.FailOpen), nil
+}
+
+// censorRouteName возвращает имя маршрута сервиса цензуры
+func censorRouteName(cfg *config.Config) string {
+	if cfg.Censor.Route == "" {
+		return "censorservice"
 	}
-	return body, nil
+	return cfg.Censor.Route
 }
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"commentservice/storage"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// defaultNewsRoute маршрут newsservice, если в конфигурации не указан другой
+const defaultNewsRoute = "newsservice"
+
+// openStorages создает хранилища комментариев и новостей по драйверам из
+// конфигурации. Для postgres при включенном auto_migrate применяет миграции.
+func openStorages(ctx context.Context, cfg *config.Config, log *slog.Logger) (storage.CommentsStorage, storage.NewsStorage, error) {
+	commentStorage, err := openCommentStorage(ctx, cfg, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newsStorage, err := openNewsStorage(cfg, log)
+	if err != nil {
+		commentStorage.Close()
+		return nil, nil, err
+	}
+
+	return commentStorage, newsStorage, nil
+}
+
+func openCommentStorage(ctx context.Context, cfg *config.Config, log *slog.Logger) (storage.CommentsStorage, error) {
+	switch cfg.GetStorageDriver() {
+	case config.StorageDriverMemory:
+		log.Warn("using in-memory comments storage, data will be lost on restart")
+		return storage.NewMemoryStorage(log), nil
+	case config.StorageDriverPostgres:
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Storage.Driver)
+	}
+
+	commentStorage, err := storage.NewCommentStorage(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to comments database: %w", err)
+	}
+
+	if cfg.App.AutoMigrate {
+		if err := autoMigrate(ctx, commentStorage); err != nil {
+			commentStorage.Close()
+			return nil, fmt.Errorf("failed to migrate comments database: %w", err)
+		}
+	}
+
+	return commentStorage, nil
+}
+
+func openNewsStorage(cfg *config.Config, log *slog.Logger) (storage.NewsStorage, error) {
+	switch cfg.GetNewsStorageDriver() {
+	case config.StorageDriverMemory:
+		return storage.NewMemoryNewsStorage(cfg.Storage.NewsIDs...), nil
+	case config.StorageDriverHTTP:
+		routeName := cfg.Storage.NewsAPI.Route
+		if routeName == "" {
+			routeName = defaultNewsRoute
+		}
+		route, err := cfg.GetRoute(routeName)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("using newsservice API for news existence checks", "route", route.BaseURL)
+		return storage.NewNewsAPIStorage(route, cfg.Storage.NewsAPI, log)
+	case config.StorageDriverPostgres:
+		newsStorage, err := storage.NewNewsStorage(cfg, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to news database: %w", err)
+		}
+		return newsStorage, nil
+	default:
+		return nil, fmt.Errorf("unknown news storage driver: %s", cfg.Storage.NewsDriver)
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// minRestartBackoff задержка перед первым перезапуском упавшего воркера
+	minRestartBackoff = time.Second
+	// maxRestartBackoff предел экспоненциального роста задержки
+	maxRestartBackoff = time.Minute
+	// stableRunDuration после такой продолжительности работы задержка сбрасывается
+	stableRunDuration = 5 * time.Minute
+)
+
+// worker фоновая задача, работающая до отмены контекста
+type worker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// supervisor запускает фоновые воркеры, перезапускает завершившиеся раньше
+// времени с экспоненциальной задержкой и дожидается их остановки.
+type supervisor struct {
+	workers []worker
+	wg      sync.WaitGroup
+	log     *slog.Logger
+}
+
+func newSupervisor(log *slog.Logger) *supervisor {
+	return &supervisor{log: log}
+}
+
+// add регистрирует воркер. Вызывается до start.
+func (s *supervisor) add(name string, run func(ctx context.Context) error) {
+	s.workers = append(s.workers, worker{name: name, run: run})
+}
+
+// start запускает все воркеры. Воркеры работают до отмены ctx.
+func (s *supervisor) start(ctx context.Context) {
+	for _, w := range s.workers {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.keepRunning(ctx, w)
+		}()
+	}
+}
+
+// wait ждет остановки всех воркеров, но не дольше, чем живет ctx
+func (s *supervisor) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop in time: %w", ctx.Err())
+	}
+}
+
+// keepRunning перезапускает воркер, пока не отменен ctx
+func (s *supervisor) keepRunning(ctx context.Context, w worker) {
+	log := s.log.With("worker", w.name)
+	backoff := minRestartBackoff
+
+	for {
+		started := time.Now()
+		err := s.runOnce(ctx, w)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) > stableRunDuration {
+			backoff = minRestartBackoff
+		}
+		log.Error("worker exited unexpectedly, restarting", "error", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRestartBackoff)
+	}
+}
+
+// runOnce запускает воркер, превращая панику в ошибку
+func (s *supervisor) runOnce(ctx context.Context, w worker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.run(ctx)
+}
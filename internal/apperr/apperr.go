@@ -0,0 +1,120 @@
+// Package apperr описывает ошибки предметной области. Каждая ошибка
+// относится к одной из категорий, по которой транспорт подбирает HTTP статус
+// или код ошибки в ответе Kafka.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Категории ошибок. Проверяются через errors.Is.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrValidation      = errors.New("validation failed")
+	ErrCensored        = errors.New("censored")
+	ErrRateLimited     = errors.New("rate limit exceeded")
+	ErrConflict        = errors.New("conflict")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Коды ошибок, передаваемые клиентам
+const (
+	CodeNotFound        = "not_found"
+	CodeValidation      = "validation"
+	CodeCensored        = "censored"
+	CodeRateLimited     = "rate_limited"
+	CodeConflict        = "conflict"
+	CodeUnauthenticated = "unauthenticated"
+	CodeForbidden       = "forbidden"
+	CodeInternal        = "internal"
+)
+
+// Error ошибка категории Kind с собственным сообщением. Используется для
+// объявления конкретных sentinel-ошибок, например storage.ErrCommentNotFound.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// New создает ошибку категории kind
+func New(kind error, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// ValidationError некорректное значение поля Field
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+// Invalid создает ValidationError для поля field
+func Invalid(field, format string, args ...any) *ValidationError {
+	return &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
+
+// NotFoundError отсутствующий ресурс Resource с идентификатором ID
+type NotFoundError struct {
+	Resource string
+	ID       int
+}
+
+// NotFound создает NotFoundError
+func NotFound(resource string, id int) *NotFoundError {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with id %d not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Unwrap() error { return ErrNotFound }
+
+// RateLimitError превышение лимита запросов. RetryAfter - через сколько
+// запрос можно повторить, 0 если неизвестно.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter.Truncate(time.Millisecond))
+	}
+	return "rate limit exceeded"
+}
+
+func (e *RateLimitError) Unwrap() error { return ErrRateLimited }
+
+// Code возвращает код категории ошибки или CodeInternal, если категория не задана
+func Code(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrValidation):
+		return CodeValidation
+	case errors.Is(err, ErrCensored):
+		return CodeCensored
+	case errors.Is(err, ErrRateLimited):
+		return CodeRateLimited
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrUnauthenticated):
+		return CodeUnauthenticated
+	case errors.Is(err, ErrForbidden):
+		return CodeForbidden
+	default:
+		return CodeInternal
+	}
+}
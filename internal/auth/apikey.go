@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// APIKeyHeader заголовок сообщения Kafka с API ключом вызывающего сервиса
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys проверяет API ключи сервисов
+type APIKeys struct {
+	keys map[[sha256.Size]byte]string
+}
+
+// NewAPIKeys создает проверку по ключам keys: имя сервиса -> ключ.
+// Пустые ключи пропускаются.
+func NewAPIKeys(keys map[string]string) *APIKeys {
+	a := &APIKeys{keys: make(map[[sha256.Size]byte]string, len(keys))}
+	for name, key := range keys {
+		if key != "" {
+			a.keys[sha256.Sum256([]byte(key))] = name
+		}
+	}
+	return a
+}
+
+// Authenticate возвращает сервис, которому принадлежит ключ. Ключи
+// сравниваются по хешу за постоянное время.
+func (a *APIKeys) Authenticate(key string) (Principal, error) {
+	if key == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+	sum := sha256.Sum256([]byte(key))
+	for known, name := range a.keys {
+		if subtle.ConstantTimeCompare(known[:], sum[:]) == 1 {
+			return Principal{Kind: KindService, ID: name, Name: name}, nil
+		}
+	}
+	return Principal{}, ErrInvalidCredentials
+}
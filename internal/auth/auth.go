@@ -0,0 +1,46 @@
+// Package auth определяет, от чьего имени выполняется запрос: пользователя
+// с JWT или сервиса с API ключом.
+package auth
+
+import (
+	"commentservice/internal/apperr"
+	"context"
+	"slices"
+)
+
+// Виды субъектов
+const (
+	KindUser    = "user"
+	KindService = "service"
+)
+
+// Principal субъект запроса. Для пользователя ID - claim sub, Name -
+// отображаемое имя; для сервиса ID - имя API ключа. Сервис действует от
+// имени пользователя, указанного в самом запросе.
+type Principal struct {
+	Kind  string
+	ID    string
+	Name  string
+	Roles []string
+}
+
+// HasRole сообщает, есть ли у субъекта роль role
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// ErrInvalidCredentials токен или ключ не прошел проверку
+var ErrInvalidCredentials = apperr.New(apperr.ErrUnauthenticated, "invalid credentials")
+
+type principalKey struct{}
+
+// NewContext возвращает контекст с субъектом запроса
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает субъекта запроса; ok == false для анонимного запроса
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew допустимое расхождение часов при проверке exp и nbf
+const clockSkew = 30 * time.Second
+
+// claims поля JWT, из которых строится Principal
+type claims struct {
+	jwt.RegisteredClaims
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Roles             []string `json:"roles"`
+}
+
+// Verifier проверяет JWT пользователей
+type Verifier struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+}
+
+// NewVerifier создает проверку токенов по настройкам cfg
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(clockSkew),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	var keyFunc jwt.Keyfunc
+	switch strings.ToUpper(cfg.Algorithm) {
+	case "HS256":
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("auth secret is required for HS256")
+		}
+		secret := []byte(cfg.Secret)
+		keyFunc = func(*jwt.Token) (any, error) { return secret, nil }
+		opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	case "RS256":
+		var err error
+		keyFunc, err = rsaKeyFunc(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %q", cfg.Algorithm)
+	}
+
+	return &Verifier{
+		parser:  jwt.NewParser(opts...),
+		keyFunc: keyFunc,
+	}, nil
+}
+
+// Verify проверяет подпись и сроки токена и возвращает пользователя
+func (v *Verifier) Verify(token string) (Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.keyFunc); err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if c.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	name := c.Name
+	if name == "" {
+		name = c.PreferredUsername
+	}
+	return Principal{
+		Kind:  KindUser,
+		ID:    c.Subject,
+		Name:  name,
+		Roles: c.Roles,
+	}, nil
+}
+
+// rsaKeyFunc загружает открытый ключ из PEM файла или набор ключей из JWKS
+// файла. Ключ из JWKS выбирается по заголовку kid токена.
+func rsaKeyFunc(cfg config.AuthConfig) (jwt.Keyfunc, error) {
+	switch {
+	case cfg.PublicKeyFile != "":
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return func(*jwt.Token) (any, error) { return key, nil }, nil
+	case cfg.JWKSFile != "":
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		return func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			if key, ok := keys[kid]; ok {
+				return key, nil
+			}
+			// Без kid подходит единственный ключ набора
+			if kid == "" && len(keys) == 1 {
+				for _, key := range keys {
+					return key, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}, nil
+	default:
+		return nil, fmt.Errorf("public_key_file or jwks_file is required for RS256")
+	}
+}
+
+// jwk ключ RSA из набора JWKS (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS читает RSA ключи подписи из JWKS файла
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: invalid exponent: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s contains no RSA signing keys", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,78 @@
+// Package censortest содержит поддельный сервис цензуры для локального
+// запуска и тестов без сети.
+package censortest
+
+import (
+	"commentservice/internal/censor"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Handler отвечает как сервис цензуры: 400 с указанием причины, если текст
+// содержит одно из запрещенных слов (без учета регистра), иначе 200.
+func Handler(banned ...string) http.Handler {
+	lowered := make([]string, 0, len(banned))
+	for _, word := range banned {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			lowered = append(lowered, word)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req censor.CheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+
+		content := strings.ToLower(req.Content)
+		for _, word := range lowered {
+			if strings.Contains(content, word) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(censor.CheckResponse{Reason: "banned word: " + word})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// NewServer запускает поддельный сервис цензуры на локальном порту.
+// Адрес сервера доступен в поле URL, после использования его нужно закрыть.
+func NewServer(banned ...string) *httptest.Server {
+	return httptest.NewServer(Handler(banned...))
+}
+
+// Unavailable оборачивает h: первые len(statuses) запросов получают ответы с
+// указанными кодами, остальные обрабатывает h. Ответы 429 содержат заголовок
+// Retry-After со значением retryAfter, если оно не пусто.
+func Unavailable(h http.Handler, retryAfter string, statuses ...int) http.Handler {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := requests
+		requests++
+		mu.Unlock()
+
+		if n >= len(statuses) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if statuses[n] == http.StatusTooManyRequests && retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(statuses[n])
+	})
+}
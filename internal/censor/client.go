@@ -0,0 +1,208 @@
+package censor
+
+import (
+	"bytes"
+	"commentservice/internal/infrastructure/config"
+	"commentservice/internal/requestid"
+	"commentservice/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPath    = "/check"
+	defaultTimeout = 2 * time.Second
+	defaultBackoff = 100 * time.Millisecond
+	// maxRetryAfter наибольшее ожидание по Retry-After, при котором попытка повторяется
+	maxRetryAfter = 10 * time.Second
+)
+
+// Policy определяет, что делать с комментарием, не прошедшим цензуру
+type Policy string
+
+const (
+	// PolicyReject отклоняет комментарий
+	PolicyReject Policy = "reject"
+	// PolicyFlag сохраняет комментарий с признаком Cens
+	PolicyFlag Policy = "flag"
+)
+
+// ParsePolicy разбирает политику из конфигурации. Пустая строка означает PolicyReject.
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", PolicyReject:
+		return PolicyReject, nil
+	case PolicyFlag:
+		return PolicyFlag, nil
+	default:
+		return "", fmt.Errorf("unknown censor policy: %s", s)
+	}
+}
+
+// Verdict результат проверки текста
+type Verdict struct {
+	Censored bool
+	Reason   string
+}
+
+// CheckRequest тело запроса к сервису цензуры
+type CheckRequest struct {
+	Content string `json:"content"`
+}
+
+// CheckResponse тело ответа сервиса цензуры на отклоненный текст
+type CheckResponse struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// Client HTTP клиент сервиса цензуры. Сервис отвечает 200 на допустимый
+// текст и 400 или 422 на недопустимый; ответы 429 и 5xx и сетевые ошибки
+// повторяются с экспоненциальной задержкой. Если ответ 429 содержит
+// Retry-After, следующая попытка выполняется не раньше указанного времени.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	log        *slog.Logger
+}
+
+// NewClient создает клиент сервиса цензуры по маршруту из конфигурации
+func NewClient(route config.Route, cfg config.CensorConfig, log *slog.Logger) (*Client, error) {
+	if route.BaseURL == "" {
+		return nil, fmt.Errorf("censor route %s has empty base_url", route.Name)
+	}
+
+	path := cfg.Path
+	if path == "" {
+		path = defaultPath
+	}
+	timeout := time.Duration(cfg.TimeoutMS) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	backoff := time.Duration(cfg.RetryBackoffMS) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	return &Client{
+		url:        strings.TrimRight(route.BaseURL, "/") + path,
+		httpClient: &http.Client{Timeout: timeout},
+		retries:    max(cfg.Retries, 0),
+		backoff:    backoff,
+		log:        log,
+	}, nil
+}
+
+// Check проверяет текст комментария
+func (c *Client) Check(ctx context.Context, content string) (Verdict, error) {
+	body, err := json.Marshal(CheckRequest{Content: content})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to encode censor request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			delay := c.backoff << (attempt - 1)
+			var throttled *throttledError
+			if errors.As(lastErr, &throttled) {
+				delay = max(delay, throttled.retryAfter)
+				// Сервис просит подождать дольше, чем осталось у запроса,
+				// или дольше разумного: повтор не выполняется
+				deadline, ok := ctx.Deadline()
+				if delay > maxRetryAfter || ok && time.Until(deadline) < delay {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return Verdict{}, fmt.Errorf("censor check canceled: %w", errors.Join(ctx.Err(), lastErr))
+			case <-time.After(delay):
+			}
+		}
+
+		verdict, retry, err := c.check(ctx, body)
+		if err == nil {
+			return verdict, nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+		c.log.Warn("censor check failed", "attempt", attempt+1, "error", err)
+	}
+
+	return Verdict{}, fmt.Errorf("censor service unavailable: %w", lastErr)
+}
+
+// check выполняет одну попытку запроса. retry сообщает, имеет ли смысл повторять.
+func (c *Client) check(ctx context.Context, body []byte) (verdict Verdict, retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, false, fmt.Errorf("failed to create censor request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	requestid.SetHeader(ctx, req.Header)
+	tracing.InjectHTTP(ctx, req.Header)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Verdict{}, ctx.Err() == nil, fmt.Errorf("failed to send censor request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return Verdict{}, false, nil
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+		var checkResp CheckResponse
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = json.Unmarshal(raw, &checkResp)
+		return Verdict{Censored: true, Reason: checkResp.Reason}, false, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return Verdict{}, true, &throttledError{retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return Verdict{}, true, fmt.Errorf("unexpected censor response code: %d", resp.StatusCode)
+	default:
+		return Verdict{}, false, fmt.Errorf("unexpected censor response code: %d", resp.StatusCode)
+	}
+}
+
+// throttledError ответ 429. retryAfter - задержка из заголовка Retry-After
+// или 0, если заголовка нет.
+type throttledError struct {
+	retryAfter time.Duration
+}
+
+func (e *throttledError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("censor service rate limited, retry after %s", e.retryAfter)
+	}
+	return "censor service rate limited"
+}
+
+// parseRetryAfter разбирает заголовок Retry-After в виде числа секунд или
+// HTTP даты. Пустое, некорректное или прошедшее значение дает 0.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package censor_test
+
+import (
+	"commentservice/internal/censor"
+	"commentservice/internal/censor/censortest"
+	"commentservice/internal/infrastructure/config"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient запускает h и создает клиент к нему с retries повторами.
+// Возвращает счетчик запросов, дошедших до сервера.
+func newTestClient(t *testing.T, h http.Handler, retries int) (*censor.Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := censor.NewClient(
+		config.Route{Name: "censor", BaseURL: srv.URL},
+		config.CensorConfig{Retries: retries, RetryBackoffMS: 1},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client, &requests
+}
+
+func TestClientCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		content      string
+		want         censor.Verdict
+		wantErr      bool
+		wantRequests int32
+	}{
+		{name: "allowed", content: "hello", wantRequests: 1},
+		{name: "censored", content: "buy SPAM now", want: censor.Verdict{Censored: true, Reason: "banned word: spam"}, wantRequests: 1},
+		{name: "server error is retried", statuses: []int{500, 502}, content: "hello", wantRequests: 3},
+		{name: "rate limit is retried", statuses: []int{429}, content: "spam", want: censor.Verdict{Censored: true, Reason: "banned word: spam"}, wantRequests: 2},
+		{name: "retries exhausted", statuses: []int{503, 503, 503}, content: "hello", wantErr: true, wantRequests: 3},
+		{name: "unexpected code is terminal", statuses: []int{404}, content: "hello", wantErr: true, wantRequests: 1},
+		{name: "unauthorized is terminal", statuses: []int{401}, content: "hello", wantErr: true, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := censortest.Unavailable(censortest.Handler("spam"), "", tt.statuses...)
+			client, requests := newTestClient(t, h, 2)
+
+			got, err := client.Check(context.Background(), tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Check() = %+v, want %+v", got, tt.want)
+			}
+			if n := requests.Load(); n != tt.wantRequests {
+				t.Fatalf("requests = %d, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	h := censortest.Unavailable(censortest.Handler(), "1", http.StatusTooManyRequests)
+	client, requests := newTestClient(t, h, 2)
+
+	start := time.Now()
+	if _, err := client.Check(context.Background(), "hello"); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %s, want at least Retry-After 1s", elapsed)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("requests = %d, want 2", n)
+	}
+}
+
+func TestClientRetryAfterBeyondDeadline(t *testing.T) {
+	h := censortest.Unavailable(censortest.Handler(), "60", http.StatusTooManyRequests)
+	client, requests := newTestClient(t, h, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// повтор не успеет до дедлайна, поэтому клиент сразу возвращает ошибку
+	start := time.Now()
+	if _, err := client.Check(ctx, "hello"); err == nil {
+		t.Fatal("Check() error = nil, want error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Check() took %s, want immediate failure", elapsed)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{" 0 ", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := censor.ParseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package censor
+
+// ParseRetryAfter открывает parseRetryAfter для тестов
+var ParseRetryAfter = parseRetryAfter
@@ -0,0 +1,92 @@
+// Package clientip хранит в контексте IP адрес клиента HTTP запроса
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedForHeader заголовок с цепочкой адресов, добавляемый прокси
+const ForwardedForHeader = "X-Forwarded-For"
+
+type contextKey struct{}
+
+// NewContext возвращает контекст с IP адресом клиента
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// FromContext извлекает IP адрес клиента; пустая строка, если его нет
+// (например, для запросов из Kafka)
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(contextKey{}).(string)
+	return ip
+}
+
+// ParseProxies разбирает адреса доверенных прокси: подсети в нотации CIDR
+// ("10.0.0.0/8") или отдельные адреса ("10.0.0.1")
+func ParseProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// FromRequest определяет IP адрес клиента. Если trustForwardedFor, цепочка
+// X-Forwarded-For просматривается справа налево, пропуская trustedProxies, и
+// берется первый адрес не из них. Левые адреса цепочки пишет сам клиент,
+// поэтому им верить нельзя. Без доверенных прокси берется самый правый адрес,
+// добавленный ближайшим прокси. Если подходящего адреса нет, используется
+// адрес соединения.
+func FromRequest(r *http.Request, trustForwardedFor bool, trustedProxies []netip.Prefix) string {
+	if trustForwardedFor {
+		if ip, ok := forwardedFor(r.Header.Values(ForwardedForHeader), trustedProxies); ok {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor выбирает адрес клиента из значений X-Forwarded-For
+func forwardedFor(values []string, trustedProxies []netip.Prefix) (string, bool) {
+	entries := strings.Split(strings.Join(values, ","), ",")
+	for i := len(entries) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(entries[i]))
+		if err != nil {
+			// Мусор в цепочке мог оставить только клиент: дальше не смотрим
+			return "", false
+		}
+		addr = addr.Unmap()
+		if i > 0 && trusted(addr, trustedProxies) {
+			continue
+		}
+		return addr.String(), true
+	}
+	return "", false
+}
+
+func trusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	proxies, err := ParseProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("ParseProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		trust      bool
+		proxies    bool
+		want       string
+	}{
+		{name: "remote addr", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "remote addr ipv6", remoteAddr: "[2001:db8::1]:5000", want: "2001:db8::1"},
+		{name: "remote addr without port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+		{name: "header ignored when untrusted", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.1"}, want: "10.0.0.2"},
+		{name: "single entry", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.1"}, trust: true, want: "198.51.100.1"},
+		{name: "right-most entry without proxies", remoteAddr: "10.0.0.2:5000", forwarded: []string{"1.1.1.1, 198.51.100.1"}, trust: true, want: "198.51.100.1"},
+		{name: "spoofed left entry skipped", remoteAddr: "10.0.0.2:5000", forwarded: []string{"1.1.1.1, 198.51.100.1, 10.0.0.5"}, trust: true, proxies: true, want: "198.51.100.1"},
+		{name: "single trusted address", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.1, 192.168.1.1"}, trust: true, proxies: true, want: "198.51.100.1"},
+		{name: "several header lines", remoteAddr: "10.0.0.2:5000", forwarded: []string{"1.1.1.1", "198.51.100.1, 10.0.0.5"}, trust: true, proxies: true, want: "198.51.100.1"},
+		{name: "all entries trusted", remoteAddr: "10.0.0.2:5000", forwarded: []string{"10.0.0.9, 10.0.0.5"}, trust: true, proxies: true, want: "10.0.0.9"},
+		{name: "garbage falls back to remote addr", remoteAddr: "10.0.0.2:5000", forwarded: []string{"1.1.1.1, not-an-ip"}, trust: true, want: "10.0.0.2"},
+		{name: "empty header", remoteAddr: "10.0.0.2:5000", trust: true, want: "10.0.0.2"},
+		{name: "ipv4-mapped ipv6", remoteAddr: "10.0.0.2:5000", forwarded: []string{"::ffff:198.51.100.1"}, trust: true, want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add(ForwardedForHeader, value)
+			}
+			trusted := proxies
+			if !tt.proxies {
+				trusted = nil
+			}
+			if got := FromRequest(r, tt.trust, trusted); got != tt.want {
+				t.Errorf("FromRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	if _, err := ParseProxies([]string{"10.0.0.0/8", "::1", "2001:db8::/32"}); err != nil {
+		t.Errorf("ParseProxies() error = %v", err)
+	}
+	if _, err := ParseProxies([]string{"proxy.local"}); err == nil {
+		t.Error("ParseProxies(proxy.local) error = nil, want error")
+	}
+}
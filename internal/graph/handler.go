@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/infrastructure/config"
+	"commentservice/internal/requestid"
+	"commentservice/internal/service"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// queryCacheSize число разобранных запросов, хранимых в кэше
+const queryCacheSize = 1000
+
+// NewHandler возвращает HTTP обработчик GraphQL API поверх commentService
+func NewHandler(commentService service.CommentService, cfg *config.Config) http.Handler {
+	srv := handler.New(NewExecutableSchema(Config{
+		Resolvers: &Resolver{commentService: commentService},
+	}))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](queryCacheSize))
+	srv.Use(extension.FixedComplexityLimit(cfg.GetGraphQLComplexityLimit()))
+	if cfg.GraphQL.Playground {
+		srv.Use(extension.Introspection{})
+	}
+	srv.SetErrorPresenter(presentError)
+
+	return withLoaders(commentService, srv)
+}
+
+// PlaygroundHandler возвращает страницу GraphQL playground для endpoint
+func PlaygroundHandler(endpoint string) http.Handler {
+	return playground.Handler("commentservice", endpoint)
+}
+
+// presentError дополняет ошибки резолверов кодом из apperr и ID запроса.
+// Текст внутренних ошибок клиенту не отдается.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if gqlErr.Err == nil {
+		// ошибка разбора или проверки запроса
+		return gqlErr
+	}
+
+	code := apperr.Code(gqlErr.Err)
+	if code == apperr.CodeInternal {
+		gqlErr.Message = "internal error"
+	}
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]any)
+	}
+	gqlErr.Extensions["code"] = code
+	var rateLimit *apperr.RateLimitError
+	if errors.As(gqlErr.Err, &rateLimit) {
+		gqlErr.Extensions["retry_after"] = int(math.Ceil(rateLimit.RetryAfter.Seconds()))
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		gqlErr.Extensions["request_id"] = id
+	}
+	return gqlErr
+}
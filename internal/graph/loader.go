@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"commentservice/internal/models"
+	"commentservice/internal/service"
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// loaderWait время, в течение которого резолверы успевают запросить ключи для одной пачки
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch максимальный размер пачки
+	loaderMaxBatch = 100
+)
+
+// loader собирает ключи, запрошенные резолверами за loaderWait, и загружает
+// их одним вызовом fetch. Загруженные значения кэшируются на время запроса.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu    sync.Mutex
+	cache map[K]V
+	batch *batch[K, V]
+}
+
+// batch пачка ключей, ожидающая загрузки
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	done   chan struct{}
+	result map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch: fetch,
+		cache: make(map[K]V),
+	}
+}
+
+// Load возвращает значение по ключу; ok == false, если fetch его не вернул
+func (l *loader[K, V]) Load(ctx context.Context, key K) (value V, ok bool, err error) {
+	l.mu.Lock()
+	if value, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		return value, true, nil
+	}
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(loaderWait, func() { l.dispatch(ctx, b) })
+	}
+	if !slices.Contains(b.keys, key) {
+		b.keys = append(b.keys, key)
+	}
+	if len(b.keys) >= loaderMaxBatch {
+		l.batch = nil
+		go l.dispatch(ctx, b)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+	if b.err != nil {
+		return value, false, b.err
+	}
+	value, ok = b.result[key]
+	return value, ok, nil
+}
+
+// dispatch загружает пачку. Вызывается по таймеру или при заполнении пачки,
+// загрузка выполняется один раз.
+func (l *loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+
+		b.result, b.err = l.fetch(ctx, b.keys)
+
+		if b.err == nil {
+			l.mu.Lock()
+			for key, value := range b.result {
+				l.cache[key] = value
+			}
+			l.mu.Unlock()
+		}
+		close(b.done)
+	})
+}
+
+// loaders набор загрузчиков одного запроса
+type loaders struct {
+	comments  *loader[int, models.Comment]
+	reactions *loader[int, models.ReactionSummary]
+	histories *loader[int, []models.CommentRevision]
+}
+
+type loadersKey struct{}
+
+// withLoaders создает загрузчики для каждого запроса, чтобы кэш не
+// переживал запрос
+func withLoaders(commentService service.CommentService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := &loaders{
+			comments:  newLoader(commentService.GetCommentsByIDs),
+			reactions: newLoader(commentService.GetReactionSummaries),
+			histories: newLoader(commentService.GetCommentHistories),
+		}
+		ctx := context.WithValue(r.Context(), loadersKey{}, l)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// loadersFrom возвращает загрузчики запроса
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
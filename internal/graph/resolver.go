@@ -0,0 +1,28 @@
+package graph
+
+//go:generate go run github.com/99designs/gqlgen generate
+
+import "commentservice/internal/service"
+
+// Resolver корневой резолвер GraphQL API поверх CommentService
+type Resolver struct {
+	commentService service.CommentService
+}
+
+// value возвращает значение необязательного аргумента или нулевое значение
+func value[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// pointers возвращает срез указателей на элементы items
+func pointers[T any](items []T) []*T {
+	result := make([]*T, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen
+
+import (
+	"commentservice/internal/graph/model"
+	"commentservice/internal/models"
+	"context"
+)
+
+// Parent is the resolver for the parent field.
+func (r *commentResolver) Parent(ctx context.Context, obj *models.Comment) (*models.Comment, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	parent, ok, err := loadersFrom(ctx).comments.Load(ctx, *obj.ParentID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &parent, nil
+}
+
+// History is the resolver for the history field.
+func (r *commentResolver) History(ctx context.Context, obj *models.Comment) ([]*models.CommentRevision, error) {
+	revisions, _, err := loadersFrom(ctx).histories.Load(ctx, obj.CommentID)
+	if err != nil {
+		return nil, err
+	}
+	return pointers(revisions), nil
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *commentResolver) Reactions(ctx context.Context, obj *models.Comment) (*models.ReactionSummary, error) {
+	if obj.Reactions != nil {
+		return obj.Reactions, nil
+	}
+	summary, _, err := loadersFrom(ctx).reactions.Load(ctx, obj.CommentID)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// AddComment is the resolver for the addComment field.
+func (r *mutationResolver) AddComment(ctx context.Context, input model.AddCommentInput) (*models.Comment, error) {
+	saved, err := r.commentService.AddComment(ctx, models.Comment{
+		NewsID:   input.NewsID,
+		ParentID: input.ParentID,
+		Content:  input.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// SetReaction is the resolver for the setReaction field.
+func (r *mutationResolver) SetReaction(ctx context.Context, commentID int, reaction string) (*models.ReactionSummary, error) {
+	summary, err := r.commentService.SetReaction(ctx, commentID, reaction)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// RemoveReaction is the resolver for the removeReaction field.
+func (r *mutationResolver) RemoveReaction(ctx context.Context, commentID int) (*models.ReactionSummary, error) {
+	summary, err := r.commentService.RemoveReaction(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Comments is the resolver for the comments field.
+func (r *newsResolver) Comments(ctx context.Context, obj *model.News, limit *int, offset *int, cursor *string, sort *string) (*models.CommentPage, error) {
+	page, err := r.commentService.GetComments(ctx, models.CommentQuery{
+		NewsID: obj.ID,
+		Limit:  value(limit),
+		Offset: value(offset),
+		Cursor: value(cursor),
+		Sort:   value(sort),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Thread is the resolver for the thread field.
+func (r *newsResolver) Thread(ctx context.Context, obj *model.News, depth *int, limit *int, offset *int) (*model.CommentThread, error) {
+	nodes, total, err := r.commentService.GetCommentThread(ctx, models.ThreadQuery{
+		NewsID:   obj.ID,
+		MaxDepth: value(depth),
+		Limit:    value(limit),
+		Offset:   value(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.CommentThread{Nodes: nodes, Total: total}, nil
+}
+
+// News is the resolver for the news field.
+func (r *queryResolver) News(ctx context.Context, id int) (*model.News, error) {
+	return &model.News{ID: id}, nil
+}
+
+// Comment is the resolver for the comment field.
+func (r *queryResolver) Comment(ctx context.Context, id int) (*models.Comment, error) {
+	comment, ok, err := loadersFrom(ctx).comments.Load(ctx, id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Mine is the resolver for the mine field.
+func (r *reactionSummaryResolver) Mine(ctx context.Context, obj *models.ReactionSummary) (*string, error) {
+	if obj.Mine == "" {
+		return nil, nil
+	}
+	return &obj.Mine, nil
+}
+
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// News returns NewsResolver implementation.
+func (r *Resolver) News() NewsResolver { return &newsResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// ReactionSummary returns ReactionSummaryResolver implementation.
+func (r *Resolver) ReactionSummary() ReactionSummaryResolver { return &reactionSummaryResolver{r} }
+
+type commentResolver struct{ *Resolver }
+type mutationResolver struct{ *Resolver }
+type newsResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type reactionSummaryResolver struct{ *Resolver }
@@ -0,0 +1,201 @@
+// Package health проверяет доступность зависимостей сервиса для проб
+// готовности оркестратора.
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Статусы зависимости и сервиса в целом
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// defaultCheckTimeout ограничивает одну проверку, если таймаут не задан
+const defaultCheckTimeout = 2 * time.Second
+
+// CheckFunc проверяет зависимость и возвращает ошибку, если она недоступна
+type CheckFunc func(ctx context.Context) error
+
+// Option настраивает проверку зависимости
+type Option func(*dependency)
+
+// NonCritical помечает зависимость как некритичную: ее недоступность
+// отражается в отчете, но не снимает готовность сервиса
+func NonCritical() Option {
+	return func(d *dependency) {
+		d.critical = false
+	}
+}
+
+// DependencyStatus результат проверки одной зависимости. LastError хранит
+// последнюю ошибку, даже если текущая проверка прошла успешно.
+type DependencyStatus struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Critical    bool       `json:"critical"`
+	LatencyMS   float64    `json:"latency_ms"`
+	CheckedAt   time.Time  `json:"checked_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Report результат проверки всех зависимостей
+type Report struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Ready сообщает, доступны ли все критичные зависимости
+func (r Report) Ready() bool {
+	return r.Status != StatusUnavailable
+}
+
+type dependency struct {
+	name     string
+	check    CheckFunc
+	critical bool
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// Checker набор проверок зависимостей
+type Checker struct {
+	mu      sync.RWMutex
+	deps    []*dependency
+	timeout time.Duration
+}
+
+// NewChecker создает набор проверок; timeout ограничивает каждую проверку
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register добавляет проверку зависимости. По умолчанию зависимость критична.
+func (c *Checker) Register(name string, check CheckFunc, opts ...Option) {
+	dep := &dependency{
+		name:     name,
+		check:    check,
+		critical: true,
+	}
+	for _, opt := range opts {
+		opt(dep)
+	}
+
+	c.mu.Lock()
+	c.deps = append(c.deps, dep)
+	c.mu.Unlock()
+}
+
+// Check параллельно проверяет все зависимости
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	deps := c.deps
+	c.mu.RUnlock()
+
+	statuses := make([]DependencyStatus, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.run(ctx, dep)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:       StatusOK,
+		Dependencies: statuses,
+	}
+	for _, status := range statuses {
+		if status.Status == StatusUp {
+			continue
+		}
+		if status.Critical {
+			report.Status = StatusUnavailable
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+// run выполняет одну проверку с таймаутом и запоминает ошибку
+func (c *Checker) run(ctx context.Context, dep *dependency) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.safeCheck(ctx)
+	latency := time.Since(start)
+
+	status := DependencyStatus{
+		Name:      dep.name,
+		Status:    StatusUp,
+		Critical:  dep.critical,
+		LatencyMS: float64(latency.Microseconds()) / 1000,
+		CheckedAt: start.UTC(),
+	}
+
+	dep.mu.Lock()
+	defer dep.mu.Unlock()
+	if err != nil {
+		status.Status = StatusDown
+		dep.lastError = err.Error()
+		dep.lastErrorAt = start.UTC()
+	}
+	if dep.lastError != "" {
+		lastErrorAt := dep.lastErrorAt
+		status.LastError = dep.lastError
+		status.LastErrorAt = &lastErrorAt
+	}
+	return status
+}
+
+// safeCheck выполняет проверку, превращая панику в ошибку
+func (d *dependency) safeCheck(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return d.check(ctx)
+}
+
+// HTTPCheck проверяет, что url отвечает. Зависимость считается
+// недоступной при сетевой ошибке или статусе 5xx.
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+		}
+		return nil
+	}
+}
@@ -4,18 +4,33 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	App       AppConfig       `yaml:"app"`
-	HTTP      HTTPConfig      `yaml:"http"`
-	Databases DatabasesConfig `yaml:"databases"`
-	Logging   LoggingConfig   `yaml:"logging"`
-	Kafka     KafkaConfig     `yaml:"kafka"`
-	Routes    []Route         `yaml:"routes"`
+	App        AppConfig        `yaml:"app"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Databases  DatabasesConfig  `yaml:"databases"`
+	Logging    LoggingConfig    `yaml:"logging"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Routes     []Route          `yaml:"routes"`
+	Censor     CensorConfig     `yaml:"censor"`
+	Moderation ModerationConfig `yaml:"moderation"`
+	Outbox     OutboxConfig     `yaml:"outbox"`
+	Health     HealthConfig     `yaml:"health"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	GraphQL    GraphQLConfig    `yaml:"graphql"`
+	Stream     StreamConfig     `yaml:"stream"`
+	Auth       AuthConfig       `yaml:"auth"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	CORS       CORSConfig       `yaml:"cors"`
 }
 
 type AppConfig struct {
@@ -24,11 +39,18 @@ type AppConfig struct {
 	WriteTimeout        int    `yaml:"write_timeout"`
 	ConnectTimeout      int    `yaml:"connect_timeout"`
 	DefaultCommentLimit int    `yaml:"default_comment_limit"`
+	MaxThreadDepth      int    `yaml:"max_thread_depth"`
+	AutoMigrate         bool   `yaml:"auto_migrate"`
+	ShutdownTimeout     int    `yaml:"shutdown_timeout"`
 }
 
+// HTTPConfig адрес основного сервера. AdminPort - порт административного
+// сервера (метрики и управление); 0 - административные маршруты не
+// выделяются на отдельный порт.
 type HTTPConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host      string `yaml:"host"`
+	Port      int    `yaml:"port"`
+	AdminPort int    `yaml:"admin_port"`
 }
 
 type DBConfig struct {
@@ -40,28 +62,240 @@ type DBConfig struct {
 	SSLMode  string `yaml:"sslmode"`
 }
 
+// Драйверы хранилища
+const (
+	StorageDriverPostgres = "postgres"
+	StorageDriverMemory   = "memory"
+	StorageDriverHTTP     = "http"
+)
+
+// StorageConfig выбор реализации хранилищ. Driver - "postgres" (по умолчанию)
+// или "memory"; для memory NewsIDs задает существующие новости, при пустом
+// списке существующей считается любая новость. NewsDriver отдельно выбирает
+// хранилище новостей: "postgres", "memory" или "http" (API newsservice);
+// по умолчанию совпадает с Driver.
+type StorageConfig struct {
+	Driver     string        `yaml:"driver"`
+	NewsDriver string        `yaml:"news_driver"`
+	NewsIDs    []int         `yaml:"news_ids"`
+	NewsAPI    NewsAPIConfig `yaml:"news_api"`
+}
+
+// NewsAPIConfig настройки обращения к API newsservice. Path содержит
+// шаблон {id}; CacheTTL и NegativeCacheTTL - время жизни в кэше
+// найденных и ненайденных новостей в секундах; CacheSize - наибольшее
+// число новостей в кэше.
+type NewsAPIConfig struct {
+	Route            string `yaml:"route"`
+	Path             string `yaml:"path"`
+	TimeoutMS        int    `yaml:"timeout_ms"`
+	CacheTTL         int    `yaml:"cache_ttl"`
+	NegativeCacheTTL int    `yaml:"negative_cache_ttl"`
+	CacheSize        int    `yaml:"cache_size"`
+	MaxConcurrency   int    `yaml:"max_concurrency"`
+}
+
 type DatabasesConfig struct {
 	News     DBConfig `yaml:"news"`
 	Comments DBConfig `yaml:"comments"`
 }
 
+// LoggingConfig настройки логирования. Level - debug, info, warn или error;
+// Format - text или json. Если задан File.Path, логи пишутся в файл
+// вместо stdout.
 type LoggingConfig struct {
-	Level  string `yaml:"level"`
-	Format string `yaml:"format"`
+	Level  string        `yaml:"level"`
+	Format string        `yaml:"format"`
+	File   LogFileConfig `yaml:"file"`
 }
 
+// LogFileConfig файл логов с ротацией: MaxSizeMB - размер, после которого
+// файл ротируется, MaxBackups и MaxAgeDays - сколько старых файлов хранить.
+type LogFileConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// Route адрес внешнего сервиса. HealthPath - путь проверки доступности,
+// по умолчанию проверяется BaseURL.
 type Route struct {
-	Name    string `yaml:"name"`
-	BaseURL string `yaml:"base_url"`
+	Name       string `yaml:"name"`
+	BaseURL    string `yaml:"base_url"`
+	HealthPath string `yaml:"health_path"`
+}
+
+// CensorConfig настройки обращения к сервису цензуры. Policy определяет,
+// что делать с непрошедшим проверку комментарием: "reject" - отклонить,
+// "flag" - сохранить с признаком cens. FailOpen разрешает сохранять
+// комментарии, если сервис цензуры недоступен.
+type CensorConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Route          string `yaml:"route"`
+	Path           string `yaml:"path"`
+	TimeoutMS      int    `yaml:"timeout_ms"`
+	Retries        int    `yaml:"retries"`
+	RetryBackoffMS int    `yaml:"retry_backoff_ms"`
+	Policy         string `yaml:"policy"`
+	FailOpen       bool   `yaml:"fail_open"`
+}
+
+// ModerationConfig настройки локального фильтра по словарям.
+// ReloadInterval - период проверки файлов словарей на изменения в секундах.
+type ModerationConfig struct {
+	Enabled        bool               `yaml:"enabled"`
+	ReloadInterval int                `yaml:"reload_interval"`
+	Dictionaries   []DictionaryConfig `yaml:"dictionaries"`
+}
+
+// DictionaryConfig файл словаря и действие при срабатывании: "reject" или "flag"
+type DictionaryConfig struct {
+	Name   string `yaml:"name"`
+	Path   string `yaml:"path"`
+	Action string `yaml:"action"`
+}
+
+// OutboxConfig настройки публикации событий из outbox.
+// PollIntervalMS - пауза между опросами пустого outbox в миллисекундах,
+// RetentionSeconds - сколько хранятся опубликованные события (по умолчанию сутки).
+type OutboxConfig struct {
+	Enabled          bool `yaml:"enabled"`
+	BatchSize        int  `yaml:"batch_size"`
+	PollIntervalMS   int  `yaml:"poll_interval_ms"`
+	RetentionSeconds int  `yaml:"retention_seconds"`
+}
+
+// HealthConfig настройки проверки готовности. TimeoutMS ограничивает
+// проверку одной зависимости, NonCritical - имена зависимостей, недоступность
+// которых не снимает готовность сервиса.
+type HealthConfig struct {
+	TimeoutMS   int      `yaml:"timeout_ms"`
+	NonCritical []string `yaml:"non_critical"`
+}
+
+// MetricsConfig настройки экспорта метрик Prometheus
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+// TracingConfig настройки трассировки OpenTelemetry. Exporter - "otlp"
+// (OTLP/HTTP на Endpoint), "stdout" или "file" (JSON в File).
+// SampleRatio - доля сохраняемых трасс от 0 до 1, по умолчанию все.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// GraphQLConfig настройки GraphQL API на /graphql. Playground включает
+// страницу playground на /graphql/playground и интроспекцию схемы.
+// ComplexityLimit - максимальная сложность запроса, по умолчанию 200.
+type GraphQLConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	Playground      bool `yaml:"playground"`
+	ComplexityLimit int  `yaml:"complexity_limit"`
+}
+
+// AuthConfig настройки аутентификации. Algorithm - "HS256" (общий Secret)
+// или "RS256" (открытый ключ в PEM из PublicKeyFile или набор ключей из
+// JWKSFile). Issuer и Audience, если заданы, сверяются с claims iss и aud.
+// Пользователи с ролью ModeratorRole (claim roles) могут редактировать и
+// удалять чужие комментарии. APIKeys - ключи сервисов, обращающихся через
+// Kafka: имя сервиса -> ключ.
+type AuthConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	Algorithm     string            `yaml:"algorithm"`
+	Secret        string            `yaml:"secret"`
+	PublicKeyFile string            `yaml:"public_key_file"`
+	JWKSFile      string            `yaml:"jwks_file"`
+	Issuer        string            `yaml:"issuer"`
+	Audience      string            `yaml:"audience"`
+	ModeratorRole string            `yaml:"moderator_role"`
+	APIKeys       map[string]string `yaml:"api_keys"`
 }
 
+// RateLimitRule не более Requests запросов за PeriodSeconds с допустимым
+// всплеском Burst (по умолчанию Requests). Requests = 0 - без ограничения.
+type RateLimitRule struct {
+	Requests      int `yaml:"requests"`
+	PeriodSeconds int `yaml:"period_seconds"`
+	Burst         int `yaml:"burst"`
+}
+
+// RateLimitConfig ограничения на добавление комментариев отдельно для
+// пользователя, IP адреса клиента и новости, а также на правки (Edit) для
+// пользователя и IP. TrustForwardedFor берет IP из X-Forwarded-For и
+// включается, только если сервис стоит за доверенным прокси. TrustedProxies -
+// адреса или подсети CIDR своих прокси, которые пропускаются в цепочке
+// X-Forwarded-For; без них берется самый правый адрес цепочки.
+type RateLimitConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	User              RateLimitRule `yaml:"user"`
+	IP                RateLimitRule `yaml:"ip"`
+	News              RateLimitRule `yaml:"news"`
+	Edit              RateLimitRule `yaml:"edit"`
+	TrustForwardedFor bool          `yaml:"trust_forwarded_for"`
+	TrustedProxies    []string      `yaml:"trusted_proxies"`
+}
+
+// CORSConfig политика CORS. AllowedOrigins - разрешенные источники: точные
+// ("https://example.com"), с поддоменами ("https://*.example.com") или "*"
+// (любой). Без источников CORS заголовки не выдаются. Пустые AllowedMethods
+// и AllowedHeaders заменяются значениями по умолчанию; ExposedHeaders -
+// заголовки ответа, доступные скриптам. MaxAgeSeconds - время кеширования
+// preflight ответа. AllowCredentials несовместим с "*".
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	ExposedHeaders   []string `yaml:"exposed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAgeSeconds    int      `yaml:"max_age_seconds"`
+}
+
+// Источники событий потоковой выдачи
+const (
+	StreamFanoutLocal = "local"
+	StreamFanoutKafka = "kafka"
+)
+
+// StreamConfig настройки потоковой выдачи событий комментариев (SSE и
+// WebSocket). Fanout - "local" (события от записей этой реплики, по
+// умолчанию) или "kafka" (события из топика comment_events, чтобы подписчики
+// любой реплики видели все изменения; требует outbox). History - число
+// последних событий новости для возобновления по Last-Event-ID, BufferSize -
+// очередь подписчика, HeartbeatSeconds - период пустых сообщений.
+// Группа потребителей для kafka задается ключом stream в kafka.consumer_group
+// и должна быть уникальна для реплики; по умолчанию строится по имени хоста.
+type StreamConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	Fanout           string `yaml:"fanout"`
+	History          int    `yaml:"history"`
+	BufferSize       int    `yaml:"buffer_size"`
+	HeartbeatSeconds int    `yaml:"heartbeat_seconds"`
+}
+
+// KafkaTopics топики запросов и ответов. CommentInput и AddCommentInput -
+// входящие запросы на список и добавление комментариев, Comments и
+// AddComment - топики соответствующих ответов. В CommentEvents публикуются
+// события жизненного цикла комментариев.
 type KafkaTopics struct {
-	CommentInput string `yaml:"comment_input"`
-	AddComment   string `yaml:"add_comment"`
+	CommentInput    string `yaml:"comment_input"`
+	AddCommentInput string `yaml:"add_comment_input"`
+	AddComment      string `yaml:"add_comment"`
 
-	Comments string `yaml:"comments"`
+	Comments      string `yaml:"comments"`
+	CommentEvents string `yaml:"comment_events"`
 }
 
+// KafkaConfig настройки Kafka. ConsumerGroup задает группу потребителей
+// по имени воркера, ключ "default" - группу для остальных воркеров.
 type KafkaConfig struct {
 	Brokers       []string          `yaml:"brokers"`
 	Topics        KafkaTopics       `yaml:"topics"`
@@ -88,11 +322,37 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config yaml: %w", err)
 	}
 
-	if err := cfg.Databases.Comments.Validate(); err != nil {
-		return nil, fmt.Errorf("validation comments database config failed: %w", err)
+	switch cfg.GetStorageDriver() {
+	case StorageDriverPostgres:
+		if err := cfg.Databases.Comments.Validate(); err != nil {
+			return nil, fmt.Errorf("validation comments database config failed: %w", err)
+		}
+	case StorageDriverMemory:
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Storage.Driver)
+	}
+	switch cfg.GetNewsStorageDriver() {
+	case StorageDriverPostgres:
+		if err := cfg.Databases.News.Validate(); err != nil {
+			return nil, fmt.Errorf("validation news database config failed : %w", err)
+		}
+	case StorageDriverMemory, StorageDriverHTTP:
+	default:
+		return nil, fmt.Errorf("unknown news storage driver: %s", cfg.Storage.NewsDriver)
 	}
-	if err := cfg.Databases.News.Validate(); err != nil {
-		return nil, fmt.Errorf("validation news database config failed : %w", err)
+	if cfg.Stream.Enabled {
+		switch cfg.GetStreamFanout() {
+		case StreamFanoutLocal:
+		case StreamFanoutKafka:
+			if !cfg.Outbox.Enabled {
+				return nil, fmt.Errorf("stream fanout %q requires outbox to be enabled", StreamFanoutKafka)
+			}
+		default:
+			return nil, fmt.Errorf("unknown stream fanout: %s", cfg.Stream.Fanout)
+		}
+	}
+	if err := cfg.CORS.Validate(); err != nil {
+		return nil, fmt.Errorf("validation cors config failed: %w", err)
 	}
 
 	log.Printf("config loaded successfully from %s", configPath)
@@ -130,20 +390,64 @@ func (db *DBConfig) Validate() error {
 	return nil
 }
 
+// Validate запрещает учетные данные для любого источника: браузер отправил
+// бы cookie и Authorization с произвольного сайта
+func (c *CORSConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			return fmt.Errorf("allow_credentials cannot be used with allowed origin \"*\"")
+		}
+	}
+	return nil
+}
+
 // Универсальный метод
 func (c *Config) GetTopic(name string) (string, error) {
 	switch name {
 	case "comment_input":
 		return c.Kafka.Topics.CommentInput, nil
+	case "add_comment_input":
+		return c.Kafka.Topics.AddCommentInput, nil
 	case "add_comment":
 		return c.Kafka.Topics.AddComment, nil
 	case "comments":
 		return c.Kafka.Topics.Comments, nil
+	case "comment_events":
+		return c.Kafka.Topics.CommentEvents, nil
 	default:
 		return "", fmt.Errorf("topic %s not found", name)
 	}
 }
 
+// GetRoute возвращает маршрут к внешнему сервису по его имени
+func (c *Config) GetRoute(name string) (Route, error) {
+	for _, route := range c.Routes {
+		if route.Name == name {
+			return route, nil
+		}
+	}
+	return Route{}, fmt.Errorf("route %s not found", name)
+}
+
+// GetStorageDriver возвращает драйвер хранилища, по умолчанию postgres
+func (c *Config) GetStorageDriver() string {
+	if c.Storage.Driver == "" {
+		return StorageDriverPostgres
+	}
+	return c.Storage.Driver
+}
+
+// GetNewsStorageDriver возвращает драйвер хранилища новостей, по умолчанию равный GetStorageDriver
+func (c *Config) GetNewsStorageDriver() string {
+	if c.Storage.NewsDriver == "" {
+		return c.GetStorageDriver()
+	}
+	return c.Storage.NewsDriver
+}
+
 func (c *Config) GetNewsDBConfig() DBConfig {
 	return c.Databases.News
 }
@@ -164,6 +468,25 @@ func (c *Config) GetPort() int {
 	return c.HTTP.Port
 }
 
+func (c *Config) GetAdminPort() int {
+	return c.HTTP.AdminPort
+}
+
+// GetMetricsPath возвращает путь метрик, по умолчанию /metrics
+func (c *Config) GetMetricsPath() string {
+	if c.Metrics.Path == "" {
+		return "/metrics"
+	}
+	return c.Metrics.Path
+}
+
+func (c *Config) GetGraphQLComplexityLimit() int {
+	if c.GraphQL.ComplexityLimit <= 0 {
+		return 200
+	}
+	return c.GraphQL.ComplexityLimit
+}
+
 func (c *Config) GetReadTimeout() time.Duration {
 	return time.Duration(c.App.ReadTimeout) * time.Second
 }
@@ -172,10 +495,36 @@ func (c *Config) GetWriteTimeout() time.Duration {
 	return time.Duration(c.App.WriteTimeout) * time.Second
 }
 
+// defaultShutdownTimeout время на остановку приложения, если оно не задано в конфигурации
+const defaultShutdownTimeout = 30 * time.Second
+
+func (c *Config) GetShutdownTimeout() time.Duration {
+	if c.App.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(c.App.ShutdownTimeout) * time.Second
+}
+
+func (c *Config) GetDefaultCommentLimit() int {
+	return c.App.DefaultCommentLimit
+}
+
+func (c *Config) GetMaxThreadDepth() int {
+	return c.App.MaxThreadDepth
+}
+
+func (c *Config) GetModerationReloadInterval() time.Duration {
+	return time.Duration(c.Moderation.ReloadInterval) * time.Second
+}
+
 func (c *Config) GetCommentInputTopic() string {
 	return c.Kafka.Topics.CommentInput
 }
 
+func (c *Config) GetAddCommentInputTopic() string {
+	return c.Kafka.Topics.AddCommentInput
+}
+
 func (c *Config) GetAddCommentTopic() string {
 	return c.Kafka.Topics.AddComment
 }
@@ -183,3 +532,69 @@ func (c *Config) GetAddCommentTopic() string {
 func (c *Config) GetCommentsTopic() string {
 	return c.Kafka.Topics.Comments
 }
+
+func (c *Config) GetCommentEventsTopic() string {
+	return c.Kafka.Topics.CommentEvents
+}
+
+func (c *Config) GetHealthCheckTimeout() time.Duration {
+	return time.Duration(c.Health.TimeoutMS) * time.Millisecond
+}
+
+// IsNonCritical сообщает, помечена ли зависимость как некритичная
+func (c *Config) IsNonCritical(name string) bool {
+	return slices.Contains(c.Health.NonCritical, name)
+}
+
+// defaultConsumerGroup группа потребителей Kafka по умолчанию
+const defaultConsumerGroup = "commentservice"
+
+// GetConsumerGroup возвращает группу потребителей для воркера name
+func (c *Config) GetConsumerGroup(name string) string {
+	if group := c.Kafka.ConsumerGroup[name]; group != "" {
+		return group
+	}
+	if group := c.Kafka.ConsumerGroup["default"]; group != "" {
+		return group
+	}
+	return defaultConsumerGroup
+}
+
+func (c *Config) GetModeratorRole() string {
+	if c.Auth.ModeratorRole == "" {
+		return "moderator"
+	}
+	return c.Auth.ModeratorRole
+}
+
+func (c *Config) GetStreamFanout() string {
+	if c.Stream.Fanout == "" {
+		return StreamFanoutLocal
+	}
+	return c.Stream.Fanout
+}
+
+// GetStreamConsumerGroup возвращает группу потребителей событий для
+// потоковой выдачи. Каждая реплика должна читать события в своей группе.
+func (c *Config) GetStreamConsumerGroup() string {
+	if group := c.Kafka.ConsumerGroup["stream"]; group != "" {
+		return group
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = strconv.Itoa(os.Getpid())
+	}
+	return c.GetConsumerGroup("default") + "-stream-" + host
+}
+
+func (c *Config) GetStreamHeartbeat() time.Duration {
+	return time.Duration(c.Stream.HeartbeatSeconds) * time.Second
+}
+
+func (c *Config) GetOutboxPollInterval() time.Duration {
+	return time.Duration(c.Outbox.PollIntervalMS) * time.Millisecond
+}
+
+func (c *Config) GetOutboxRetention() time.Duration {
+	return time.Duration(c.Outbox.RetentionSeconds) * time.Second
+}
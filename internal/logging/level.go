@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	httputils "github.com/Fau1con/renderresponse"
+)
+
+// levelRequest тело запроса на смену уровня
+type levelRequest struct {
+	Level string `json:"level"`
+}
+
+// levelResponse текущий уровень логирования
+type levelResponse struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler показывает (GET) и меняет (PUT) уровень логирования во время
+// работы. Новый уровень передается в теле {"level": "debug"} или в
+// параметре запроса level.
+func LevelHandler(level *slog.LevelVar, log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !httputils.ValidateMethod(w, r, http.MethodGet, http.MethodPut) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			httputils.RenderJSON(w, levelResponse{Level: level.Level().String()}, http.StatusOK)
+			return
+		}
+
+		req := levelRequest{Level: r.URL.Query().Get("level")}
+		if req.Level == "" {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				httputils.RenderError(w, "failed to decode request body", http.StatusBadRequest, err)
+				return
+			}
+		}
+		parsed, err := ParseLevel(req.Level)
+		if err != nil {
+			httputils.RenderError(w, "invalid log level", http.StatusBadRequest, err)
+			return
+		}
+
+		previous := level.Level()
+		level.Set(parsed)
+		log.InfoContext(r.Context(), "log level changed", "from", previous, "to", parsed)
+		httputils.RenderJSON(w, levelResponse{Level: parsed.String()}, http.StatusOK)
+	})
+}
@@ -0,0 +1,79 @@
+// Package logging создает логгер приложения по конфигурации и дополняет
+// записи атрибутами из контекста: ID запроса, новостью, смещением Kafka.
+package logging
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Форматы вывода
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel разбирает уровень логирования: debug, info, warn или error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level: %q", s)
+	}
+	return level, nil
+}
+
+// New создает логгер по настройкам. Уровень хранится в возвращаемом
+// LevelVar и может меняться во время работы. Возвращаемый io.Closer
+// закрывает файл вывода, если он используется.
+func New(cfg config.LoggingConfig) (*slog.Logger, *slog.LevelVar, io.Closer, error) {
+	level := new(slog.LevelVar)
+	if cfg.Level != "" {
+		parsed, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		level.Set(parsed)
+	}
+
+	out, closer := output(cfg.File)
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case FormatText, "":
+		handler = slog.NewTextHandler(out, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		closer.Close()
+		return nil, nil, nil, fmt.Errorf("unknown log format: %q", cfg.Format)
+	}
+
+	return slog.New(NewContextHandler(handler)), level, closer, nil
+}
+
+// output возвращает файл с ротацией, если он задан, иначе stdout
+func output(cfg config.LogFileConfig) (io.Writer, io.Closer) {
+	if cfg.Path == "" {
+		return os.Stdout, nopCloser{}
+	}
+	file := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}
+	return file, file
+}
+
+// nopCloser используется для stdout, который закрывать не нужно
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,186 @@
+// Package metrics собирает метрики Prometheus слоев HTTP, сервиса,
+// хранилища и Kafka.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace префикс имен всех метрик сервиса
+const namespace = "commentservice"
+
+// Metrics коллекторы сервиса в собственном реестре
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	commentsAdded    *prometheus.CounterVec
+	commentsRejected *prometheus.CounterVec
+
+	storageDuration *prometheus.HistogramVec
+
+	kafkaConsumed        *prometheus.CounterVec
+	kafkaConsumeErrors   *prometheus.CounterVec
+	kafkaConsumerLag     *prometheus.GaugeVec
+	kafkaProduced        *prometheus.CounterVec
+	kafkaProduceErrors   *prometheus.CounterVec
+	kafkaProduceDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+
+		commentsAdded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "comments",
+			Name:      "added_total",
+			Help:      "Comments saved, split by whether they were flagged by moderation.",
+		}, []string{"censored"}),
+		commentsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "comments",
+			Name:      "rejected_total",
+			Help:      "Comments that were not saved, by rejection reason.",
+		}, []string{"reason"}),
+
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "query_duration_seconds",
+			Help:      "Storage method latency.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method"}),
+
+		kafkaConsumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "messages_consumed_total",
+			Help:      "Kafka messages processed by worker.",
+		}, []string{"worker"}),
+		kafkaConsumeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "consume_errors_total",
+			Help:      "Kafka read, reply or commit errors by worker.",
+		}, []string{"worker"}),
+		kafkaConsumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "consumer_lag",
+			Help:      "Messages left in the partition after the last processed one, by worker.",
+		}, []string{"worker"}),
+		kafkaProduced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "messages_produced_total",
+			Help:      "Kafka messages published by topic.",
+		}, []string{"topic"}),
+		kafkaProduceErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "produce_errors_total",
+			Help:      "Kafka publish errors by topic.",
+		}, []string{"topic"}),
+		kafkaProduceDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "produce_duration_seconds",
+			Help:      "Time to publish a message until it is acknowledged by brokers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"topic"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.commentsAdded,
+		m.commentsRejected,
+		m.storageDuration,
+		m.kafkaConsumed,
+		m.kafkaConsumeErrors,
+		m.kafkaConsumerLag,
+		m.kafkaProduced,
+		m.kafkaProduceErrors,
+		m.kafkaProduceDuration,
+	)
+	return m
+}
+
+// Handler отдает метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterPool добавляет статистику пула соединений базы name
+func (m *Metrics) RegisterPool(name string, pool PoolStater) {
+	m.registry.MustRegister(newPoolCollector(name, pool))
+}
+
+// ObserveHTTPRequest учитывает обработанный HTTP запрос
+func (m *Metrics) ObserveHTTPRequest(route, method string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// CommentAdded учитывает сохраненный комментарий
+func (m *Metrics) CommentAdded(censored bool) {
+	m.commentsAdded.WithLabelValues(strconv.FormatBool(censored)).Inc()
+}
+
+// CommentRejected учитывает комментарий, который не был сохранен
+func (m *Metrics) CommentRejected(reason string) {
+	m.commentsRejected.WithLabelValues(reason).Inc()
+}
+
+// ObserveQuery учитывает длительность метода хранилища
+func (m *Metrics) ObserveQuery(method string, duration time.Duration) {
+	m.storageDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// ObserveConsume учитывает обработку сообщения воркером. lag - число
+// сообщений в партиции после обработанного, отрицательное значение неизвестно.
+func (m *Metrics) ObserveConsume(worker string, lag int64, err error) {
+	if err != nil {
+		m.kafkaConsumeErrors.WithLabelValues(worker).Inc()
+		return
+	}
+	m.kafkaConsumed.WithLabelValues(worker).Inc()
+	if lag >= 0 {
+		m.kafkaConsumerLag.WithLabelValues(worker).Set(float64(lag))
+	}
+}
+
+// ObserveProduce учитывает публикацию сообщения
+func (m *Metrics) ObserveProduce(topic string, duration time.Duration, err error) {
+	if err != nil {
+		m.kafkaProduceErrors.WithLabelValues(topic).Inc()
+		return
+	}
+	m.kafkaProduced.WithLabelValues(topic).Inc()
+	m.kafkaProduceDuration.WithLabelValues(topic).Observe(duration.Seconds())
+}
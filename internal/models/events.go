@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Типы событий жизненного цикла комментария
+const (
+	EventCommentCreated  = "comment.created"
+	EventCommentUpdated  = "comment.updated"
+	EventCommentDeleted  = "comment.deleted"
+	EventCommentCensored = "comment.censored"
+)
+
+// CommentEvent событие жизненного цикла комментария, публикуемое в Kafka.
+// RequestID - ID запроса, вызвавшего изменение.
+type CommentEvent struct {
+	Type       string    `json:"type"`
+	NewsID     int       `json:"news_id"`
+	CommentID  int       `json:"comment_id"`
+	Comment    *Comment  `json:"comment,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// OutboxEvent запись таблицы outbox: закодированное CommentEvent,
+// ожидающее публикации
+type OutboxEvent struct {
+	ID        int64
+	Type      string
+	NewsID    int
+	Payload   []byte
+	CreatedAt time.Time
+}
@@ -2,27 +2,137 @@ package models
 
 import "time"
 
+// Comment комментарий к новости. Удаленный комментарий остается в ветке
+// как "надгробие": Deleted выставлен, Content пуст. CensReason указывает
+// правило модерации, из-за которого выставлен Cens. AuthorID и AuthorName -
+// ID и отображаемое имя автора; пусты у комментариев, оставленных до
+// появления аутентификации. Reactions заполняется, только если сводка
+// реакций запрошена.
 type Comment struct {
-	CommentID int       `json:"coment_id"`
-	NewsID    int       `json:"news_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	Cens      bool      `json:"cens"`
+	CommentID  int       `json:"coment_id"`
+	NewsID     int       `json:"news_id"`
+	ParentID   *int      `json:"parent_id,omitempty"`
+	AuthorID   string    `json:"author_id,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Deleted    bool      `json:"deleted"`
+	Cens       bool      `json:"cens"`
+	CensReason string    `json:"cens_reason,omitempty"`
+
+	Reactions *ReactionSummary `json:"reactions,omitempty"`
+}
+
+// Реакции на комментарий: голос за или против
+const (
+	ReactionUp   = "up"
+	ReactionDown = "down"
+)
+
+// ValidReaction проверяет, что реакция известна
+func ValidReaction(reaction string) bool {
+	return reaction == ReactionUp || reaction == ReactionDown
+}
+
+// ReactionSummary сводка реакций на комментарий. Score - разность голосов за
+// и против, Mine - реакция пользователя, запросившего сводку, или пусто.
+type ReactionSummary struct {
+	Up    int    `json:"up"`
+	Down  int    `json:"down"`
+	Score int    `json:"score"`
+	Mine  string `json:"mine,omitempty"`
+}
+
+// CommentRevision предыдущая версия текста комментария, сохраняемая при каждом редактировании
+type CommentRevision struct {
+	RevisionID int       `json:"revision_id"`
+	CommentID  int       `json:"comment_id"`
+	Content    string    `json:"content"`
+	EditedAt   time.Time `json:"edited_at"`
+}
+
+// CommentNode узел дерева комментариев. Depth - уровень вложенности (0 для
+// корневых комментариев), ReplyCount - число прямых ответов, включая те,
+// что не попали в дерево из-за ограничения глубины.
+type CommentNode struct {
+	Comment
+	Depth      int            `json:"depth"`
+	ReplyCount int            `json:"reply_count"`
+	Replies    []*CommentNode `json:"replies"`
 }
 
-// Request/Response структуры для Kafka
+// Порядок комментариев в выборке. SortOldest используется по умолчанию.
+const (
+	SortOldest        = "oldest"
+	SortNewest        = "newest"
+	SortTop           = "top"
+	SortBest          = "best"
+	SortHot           = "hot"
+	SortControversial = "controversial"
+)
+
+// ValidSort проверяет, что порядок известен; пустой порядок означает SortOldest
+func ValidSort(sort string) bool {
+	switch sort {
+	case "", SortOldest, SortNewest, SortTop, SortBest, SortHot, SortControversial:
+		return true
+	}
+	return false
+}
+
+// CommentQuery параметры выборки страницы комментариев новости в порядке
+// Sort. Если задан Cursor, выборка идет по ключу сортировки, а Offset
+// игнорируется; курсор действителен только для того же порядка.
+// WithReactions добавляет к комментариям сводку реакций.
+type CommentQuery struct {
+	NewsID        int
+	Limit         int
+	Offset        int
+	Cursor        string
+	Sort          string
+	WithReactions bool
+}
+
+// CommentPage страница комментариев с курсорами на соседние страницы
+type CommentPage struct {
+	Comments   []Comment `json:"comments"`
+	Total      int       `json:"total"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+}
+
+// ThreadQuery параметры выборки дерева комментариев новости не глубже
+// MaxDepth уровней. Limit и Offset выбирают страницу корневых комментариев;
+// Limit <= 0 означает все.
+type ThreadQuery struct {
+	NewsID   int
+	MaxDepth int
+	Limit    int
+	Offset   int
+}
+
+// Request/Response структуры для Kafka. В ответах с ошибкой Error содержит
+// код ошибки (см. apperr), а Detail - ее описание.
 type ListCommentRequest struct {
 	NewsID    string `json:"news_id"`
 	Limit     int    `json:"limit"`
 	Offset    int    `json:"offset"`
+	Cursor    string `json:"cursor,omitempty"`
+	Sort      string `json:"sort,omitempty"`
+	Reactions bool   `json:"reactions,omitempty"`
 	RequestID string `json:"request_id"`
 }
 
 type ListCommentResponse struct {
-	Data      []Comment `json:"data"`
-	RequestID string    `json:"request_id"`
-	Status    string    `json:"status"`
-	Error     string    `json:"error"`
+	Data       []Comment `json:"data"`
+	Total      int       `json:"total"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+	RequestID  string    `json:"request_id"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error"`
+	Detail     string    `json:"detail,omitempty"`
 }
 
 type AddCommentRequest struct {
@@ -30,8 +140,14 @@ type AddCommentRequest struct {
 	RequestID string  `json:"request_id"`
 }
 
+// AddCommentResponse ответ на добавление комментария. При превышении лимита
+// Status равен "rate_limited", а RetryAfter - через сколько секунд можно
+// повторить запрос.
 type AddCommentResponse struct {
-	RequestID string `json:"request_id"`
-	Status    string `json:"status"`
-	Error     string `json:"error"`
+	Data       *Comment `json:"data,omitempty"`
+	RequestID  string   `json:"request_id"`
+	Status     string   `json:"status"`
+	Error      string   `json:"error"`
+	Detail     string   `json:"detail,omitempty"`
+	RetryAfter int      `json:"retry_after,omitempty"`
 }
@@ -0,0 +1,257 @@
+// Package moderation реализует локальный фильтр нецензурной лексики и
+// стоп-слов по словарям из файлов, не требующий обращения к censorservice.
+package moderation
+
+import (
+	"bufio"
+	"commentservice/internal/infrastructure/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// regexPrefix отмечает строку словаря, содержащую регулярное выражение
+const regexPrefix = "re:"
+
+// Action действие с комментарием, сработавшим на правило словаря
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+// Result результат проверки текста. Rule заполняется, если сработало правило.
+type Result struct {
+	Action Action
+	Rule   string
+}
+
+// dictionary скомпилированный словарь одного файла
+type dictionary struct {
+	name    string
+	path    string
+	action  Action
+	words   map[string]string
+	phrases []phrase
+	regexps []rule
+	modTime time.Time
+	size    int64
+}
+
+// phrase фраза словаря: слова скелета через пробел и исходная строка файла.
+// Фразы хранятся в порядке файла, чтобы при нескольких совпадениях
+// срабатывала одна и та же.
+type phrase struct {
+	skel string
+	line string
+}
+
+// rule регулярное выражение словаря вместе с исходным текстом из файла
+type rule struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// Filter проверяет текст по словарям. Словари перечитываются при изменении
+// файлов, если запущен Watch; проверки во время перечитывания не блокируются.
+type Filter struct {
+	configs []config.DictionaryConfig
+	dicts   atomic.Pointer[[]*dictionary]
+	log     *slog.Logger
+}
+
+// NewFilter загружает словари, перечисленные в конфигурации
+func NewFilter(cfg config.ModerationConfig, log *slog.Logger) (*Filter, error) {
+	f := &Filter{
+		configs: cfg.Dictionaries,
+		log:     log,
+	}
+
+	dicts := make([]*dictionary, 0, len(cfg.Dictionaries))
+	for _, dictCfg := range cfg.Dictionaries {
+		dict, err := loadDictionary(dictCfg)
+		if err != nil {
+			return nil, err
+		}
+		dicts = append(dicts, dict)
+	}
+	f.dicts.Store(&dicts)
+
+	return f, nil
+}
+
+// Check проверяет текст. Если сработали правила нескольких словарей,
+// побеждает самое строгое действие.
+func (f *Filter) Check(content string) Result {
+	lowered := strings.ToLower(content)
+	skel := skeleton(content)
+	tokens := words(skel)
+
+	result := Result{Action: ActionAllow}
+	for _, dict := range *f.dicts.Load() {
+		rule, ok := dict.match(lowered, skel, tokens)
+		if !ok {
+			continue
+		}
+		if result.Action == ActionAllow || dict.action == ActionReject {
+			result = Result{Action: dict.action, Rule: dict.name + ":" + rule}
+		}
+		if result.Action == ActionReject {
+			break
+		}
+	}
+	return result
+}
+
+// Watch проверяет файлы словарей раз в interval и перечитывает изменившиеся.
+// Если файл не удалось перечитать, продолжает использоваться старая версия.
+// Работает до отмены ctx.
+func (f *Filter) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			f.reloadChanged()
+		}
+	}
+}
+
+// reloadChanged перечитывает словари, у которых изменились время модификации или размер
+func (f *Filter) reloadChanged() {
+	current := *f.dicts.Load()
+	next := make([]*dictionary, len(current))
+	changed := false
+
+	for i, dict := range current {
+		next[i] = dict
+
+		info, err := os.Stat(dict.path)
+		if err != nil {
+			f.log.Warn("failed to stat moderation dictionary", "dictionary", dict.name, "error", err)
+			continue
+		}
+		if info.ModTime().Equal(dict.modTime) && info.Size() == dict.size {
+			continue
+		}
+
+		reloaded, err := loadDictionary(f.configs[i])
+		if err != nil {
+			f.log.Error("failed to reload moderation dictionary", "dictionary", dict.name, "error", err)
+			continue
+		}
+		next[i] = reloaded
+		changed = true
+		f.log.Info("moderation dictionary reloaded",
+			"dictionary", dict.name,
+			"words", len(reloaded.words),
+			"regexps", len(reloaded.regexps))
+	}
+
+	if changed {
+		f.dicts.Store(&next)
+	}
+}
+
+// match ищет первое сработавшее правило словаря
+func (d *dictionary) match(lowered, skel string, tokens []string) (string, bool) {
+	for _, token := range tokens {
+		if word, ok := d.words[token]; ok {
+			return word, true
+		}
+	}
+	if len(d.phrases) > 0 {
+		joined := " " + strings.Join(tokens, " ") + " "
+		for _, p := range d.phrases {
+			if strings.Contains(joined, " "+p.skel+" ") {
+				return p.line, true
+			}
+		}
+	}
+	for _, r := range d.regexps {
+		if r.re.MatchString(lowered) || r.re.MatchString(skel) {
+			return regexPrefix + r.pattern, true
+		}
+	}
+	return "", false
+}
+
+// loadDictionary читает файл словаря. Каждая строка - слово, фраза или, с
+// префиксом "re:", регулярное выражение; пустые строки и строки с # пропускаются.
+func loadDictionary(cfg config.DictionaryConfig) (*dictionary, error) {
+	action := Action(strings.ToLower(cfg.Action))
+	switch action {
+	case "":
+		action = ActionReject
+	case ActionReject, ActionFlag:
+	default:
+		return nil, fmt.Errorf("unknown action %q for dictionary %s", cfg.Action, cfg.Path)
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = filepath.Base(cfg.Path)
+	}
+
+	file, err := os.Open(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dictionary %s: %w", cfg.Path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat dictionary %s: %w", cfg.Path, err)
+	}
+
+	dict := &dictionary{
+		name:    name,
+		path:    cfg.Path,
+		action:  action,
+		words:   make(map[string]string),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if pattern, ok := strings.CutPrefix(line, regexPrefix); ok {
+			pattern = strings.TrimSpace(pattern)
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp at %s:%d: %w", cfg.Path, lineNo, err)
+			}
+			dict.regexps = append(dict.regexps, rule{pattern: pattern, re: re})
+			continue
+		}
+
+		switch tokens := words(skeleton(line)); len(tokens) {
+		case 0:
+		case 1:
+			dict.words[tokens[0]] = line
+		default:
+			dict.phrases = append(dict.phrases, phrase{skel: strings.Join(tokens, " "), line: line})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary %s: %w", cfg.Path, err)
+	}
+
+	return dict, nil
+}
@@ -0,0 +1,169 @@
+package moderation
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeDictionary записывает словарь во временный каталог теста
+func writeDictionary(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write dictionary: %v", err)
+	}
+	return path
+}
+
+func newTestFilter(t *testing.T, dicts ...config.DictionaryConfig) *Filter {
+	t.Helper()
+	f, err := NewFilter(config.ModerationConfig{Dictionaries: dicts}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+	return f
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"spam", "spam"},
+		{"SPAM", "spam"},
+		// кириллические омоглифы
+		{"ХЕР", "xep"},
+		{"сурер", "cypep"},
+		// leet-замены
+		{"x3p", "xep"},
+		{"$p@m", "spam"},
+		{"h4ck3r", "hacker"},
+		{"c00l", "cool"},
+		// символы без замены не меняются
+		{"шлюз", "шлюз"},
+	}
+	for _, tt := range tests {
+		if got := skeleton(tt.in); got != tt.want {
+			t.Errorf("skeleton(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+	flag := config.DictionaryConfig{
+		Name:   "flag",
+		Path:   writeDictionary(t, "flag.txt", "# спорные слова\nspam\nbuy now\nre:\\bcasino\\d+\\b\n"),
+		Action: "flag",
+	}
+	reject := config.DictionaryConfig{
+		Name:   "reject",
+		Path:   writeDictionary(t, "reject.txt", "badword\n"),
+		Action: "reject",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    Result
+	}{
+		{name: "clean", content: "hello world", want: Result{Action: ActionAllow}},
+		{name: "word", content: "this is SPAM", want: Result{Action: ActionFlag, Rule: "flag:spam"}},
+		{name: "word with homoglyphs", content: "this is $р@м", want: Result{Action: ActionFlag, Rule: "flag:spam"}},
+		{name: "word is not substring", content: "spammer", want: Result{Action: ActionAllow}},
+		{name: "phrase", content: "BUY   n0w!", want: Result{Action: ActionFlag, Rule: "flag:buy now"}},
+		{name: "regexp", content: "visit casino777", want: Result{Action: ActionFlag, Rule: "flag:re:\\bcasino\\d+\\b"}},
+		{name: "reject", content: "b4dw0rd", want: Result{Action: ActionReject, Rule: "reject:badword"}},
+		{name: "strictest wins", content: "spam badword", want: Result{Action: ActionReject, Rule: "reject:badword"}},
+	}
+
+	// результат не зависит от порядка словарей в конфигурации
+	orders := map[string][]config.DictionaryConfig{
+		"flag first":   {flag, reject},
+		"reject first": {reject, flag},
+	}
+	for order, dicts := range orders {
+		f := newTestFilter(t, dicts...)
+		for _, tt := range tests {
+			t.Run(order+"/"+tt.name, func(t *testing.T) {
+				if got := f.Check(tt.content); got != tt.want {
+					t.Fatalf("Check(%q) = %+v, want %+v", tt.content, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestFilterPhraseOrder(t *testing.T) {
+	f := newTestFilter(t, config.DictionaryConfig{
+		Name: "phrases",
+		Path: writeDictionary(t, "phrases.txt", "free money\nmoney now\nfree money now\n"),
+	})
+
+	// совпадают все три фразы, срабатывает первая в файле
+	want := Result{Action: ActionReject, Rule: "phrases:free money"}
+	for i := 0; i < 50; i++ {
+		if got := f.Check("get free money now"); got != want {
+			t.Fatalf("Check() = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestFilterReloadChanged(t *testing.T) {
+	path := writeDictionary(t, "words.txt", "spam\n")
+	f := newTestFilter(t, config.DictionaryConfig{Name: "words", Path: path})
+
+	// rewrite меняет содержимое и время модификации файла, чтобы изменение
+	// было заметно и при грубом разрешении времени файловой системы
+	modTime := time.Now()
+	rewrite := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to rewrite dictionary: %v", err)
+		}
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to touch dictionary: %v", err)
+		}
+	}
+
+	steps := []struct {
+		name    string
+		content string
+		// check текст и ожидаемое действие после перечитывания
+		check map[string]Action
+	}{
+		{
+			name:    "invalid regexp keeps old dictionary",
+			content: "scam\nre:(unclosed\n",
+			check:   map[string]Action{"spam": ActionReject, "scam": ActionAllow},
+		},
+		{
+			name:    "valid file replaces dictionary",
+			content: "scam\n",
+			check:   map[string]Action{"spam": ActionAllow, "scam": ActionReject},
+		},
+	}
+	for _, step := range steps {
+		rewrite(step.content)
+		f.reloadChanged()
+		for content, want := range step.check {
+			if got := f.Check(content).Action; got != want {
+				t.Fatalf("%s: Check(%q) = %s, want %s", step.name, content, got, want)
+			}
+		}
+	}
+
+	// удаленный файл тоже не сбрасывает загруженный словарь
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove dictionary: %v", err)
+	}
+	f.reloadChanged()
+	if got := f.Check("scam").Action; got != ActionReject {
+		t.Fatalf("Check(scam) after remove = %s, want %s", got, ActionReject)
+	}
+}
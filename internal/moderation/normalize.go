@@ -0,0 +1,34 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// homoglyphs приводит похожие по написанию кириллические символы и
+// "leet"-замены к одному латинскому символу, чтобы "хер", "xep" и "x3p"
+// давали одинаковый скелет.
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i',
+	'ї': 'i', 'ј': 'j', 'ѕ': 's', 'һ': 'h', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	'0': 'o', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's',
+}
+
+// skeleton приводит текст к нижнему регистру и заменяет омоглифы
+func skeleton(s string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if mapped, ok := homoglyphs[r]; ok {
+			return mapped
+		}
+		return r
+	}, s)
+}
+
+// words разбивает скелет текста на слова
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,145 @@
+// Package outbox публикует события комментариев из таблицы outbox в Kafka.
+package outbox
+
+import (
+	"commentservice/internal/models"
+	"commentservice/storage"
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	kfk "github.com/Fau1con/kafkawrapper"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultRetention    = 24 * time.Hour
+	// pruneInterval период удаления опубликованных событий
+	pruneInterval = time.Minute
+)
+
+// KeyedProducer публикует сообщения с ключом партиционирования
+type KeyedProducer interface {
+	SendKeyedMessage(ctx context.Context, topic string, key, message []byte) error
+}
+
+// Relay периодически забирает неопубликованные события из outbox и
+// публикует их в Kafka. Событие отмечается опубликованным только после
+// подтверждения записи, поэтому доставка - "как минимум один раз". Ключом
+// сообщения служит news_id; если событие новости не удалось опубликовать,
+// следующие события той же новости откладываются до следующего прохода.
+// Опубликованные события хранятся retention и затем удаляются.
+type Relay struct {
+	store        storage.OutboxStorage
+	producer     kfk.Prod
+	topic        string
+	batchSize    int
+	pollInterval time.Duration
+	retention    time.Duration
+	log          *slog.Logger
+}
+
+func NewRelay(
+	store storage.OutboxStorage,
+	producer kfk.Prod,
+	topic string,
+	batchSize int,
+	pollInterval time.Duration,
+	retention time.Duration,
+	log *slog.Logger,
+) *Relay {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	return &Relay{
+		store:        store,
+		producer:     producer,
+		topic:        topic,
+		batchSize:    batchSize,
+		pollInterval: pollInterval,
+		retention:    retention,
+		log:          log.With("worker", "outbox_relay"),
+	}
+}
+
+// Run публикует события до отмены ctx. Пока в outbox есть полная пачка
+// событий, следующая выбирается без паузы. Раз в pruneInterval удаляются
+// события, опубликованные раньше retention.
+func (r *Relay) Run(ctx context.Context) error {
+	r.log.InfoContext(ctx, "outbox relay started", "topic", r.topic)
+	var lastPrune time.Time
+	for {
+		if time.Since(lastPrune) >= pruneInterval {
+			r.prune(ctx)
+			lastPrune = time.Now()
+		}
+
+		n, err := r.store.RelayOutbox(ctx, r.batchSize, r.publish)
+		if err != nil && ctx.Err() == nil {
+			r.log.ErrorContext(ctx, "failed to relay outbox", "error", err)
+		}
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			r.log.InfoContext(ctx, "outbox relay stopped")
+			return nil
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// prune удаляет устаревшие опубликованные события
+func (r *Relay) prune(ctx context.Context) {
+	n, err := r.store.PruneOutbox(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.ErrorContext(ctx, "failed to prune outbox", "error", err)
+		}
+		return
+	}
+	if n > 0 {
+		r.log.InfoContext(ctx, "published outbox events pruned", "count", n)
+	}
+}
+
+// publish отправляет события по порядку и возвращает ID опубликованных
+func (r *Relay) publish(ctx context.Context, events []models.OutboxEvent) []int64 {
+	published := make([]int64, 0, len(events))
+	failedNews := make(map[int]bool)
+
+	for _, event := range events {
+		if failedNews[event.NewsID] {
+			continue
+		}
+		if err := r.send(ctx, event); err != nil {
+			r.log.ErrorContext(ctx, "failed to publish outbox event",
+				"event_id", event.ID,
+				"type", event.Type,
+				"news_id", event.NewsID,
+				"error", err)
+			failedNews[event.NewsID] = true
+			continue
+		}
+		published = append(published, event.ID)
+	}
+
+	return published
+}
+
+func (r *Relay) send(ctx context.Context, event models.OutboxEvent) error {
+	if keyed, ok := r.producer.(KeyedProducer); ok {
+		return keyed.SendKeyedMessage(ctx, r.topic, []byte(strconv.Itoa(event.NewsID)), event.Payload)
+	}
+	return r.producer.SendMessage(ctx, r.topic, event.Payload)
+}
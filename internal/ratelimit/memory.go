@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval период удаления заполненных корзин
+const sweepInterval = time.Minute
+
+// bucket корзина: число токенов на момент updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill возвращает число токенов на момент now
+func (b *bucket) refill(now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.updated).Seconds()*b.limit.Rate
+	return math.Min(tokens, float64(b.limit.Burst))
+}
+
+// MemoryLimiter хранит корзины в памяти процесса; лимиты действуют для
+// каждой реплики отдельно
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow списывает по токену из корзин всех запросов, если в каждой есть
+// токен. Запросы с нулевым лимитом пропускаются.
+func (l *MemoryLimiter) Allow(_ context.Context, requests ...Request) (Decision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	decision := Decision{Allowed: true}
+	for _, req := range requests {
+		if req.Limit.Unlimited() {
+			continue
+		}
+		b := l.bucket(req, now)
+		if tokens := b.refill(now); tokens < 1 {
+			// Округление вверх: клиент, выждавший RetryAfter, должен пройти
+			wait := time.Duration(math.Ceil((1-tokens)/req.Limit.Rate*1000)) * time.Millisecond
+			decision.Allowed = false
+			decision.RetryAfter = max(decision.RetryAfter, wait)
+		}
+	}
+	if !decision.Allowed {
+		return decision, nil
+	}
+
+	for _, req := range requests {
+		if req.Limit.Unlimited() {
+			continue
+		}
+		b := l.buckets[req.Key]
+		b.tokens = b.refill(now) - 1
+		b.updated = now
+	}
+	return decision, nil
+}
+
+// bucket возвращает корзину ключа, создавая полную при первом обращении
+func (l *MemoryLimiter) bucket(req Request, now time.Time) *bucket {
+	b, ok := l.buckets[req.Key]
+	if !ok {
+		b = &bucket{tokens: float64(req.Limit.Burst), updated: now}
+		l.buckets[req.Key] = b
+	}
+	b.limit = req.Limit
+	return b
+}
+
+// Run периодически удаляет заполненные корзины, пока не отменен ctx
+func (l *MemoryLimiter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			l.sweep(now)
+		}
+	}
+}
+
+func (l *MemoryLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if b.refill(now) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
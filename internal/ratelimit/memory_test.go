@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestLimiter создает лимитер с часами, которые двигает тест
+func newTestLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func allow(t *testing.T, l *MemoryLimiter, requests ...Request) Decision {
+	t.Helper()
+	decision, err := l.Allow(context.Background(), requests...)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	return decision
+}
+
+func TestMemoryLimiterRefill(t *testing.T) {
+	l, now := newTestLimiter()
+	req := Request{Key: "user:1", Limit: Every(2, time.Second, 2)}
+
+	steps := []struct {
+		advance time.Duration
+		want    bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{250 * time.Millisecond, false},
+		{250 * time.Millisecond, true},
+		{0, false},
+		// корзина не наполняется больше Burst
+		{time.Hour, true},
+		{0, true},
+		{0, false},
+	}
+	for i, step := range steps {
+		*now = now.Add(step.advance)
+		if got := allow(t, l, req).Allowed; got != step.want {
+			t.Fatalf("step %d: Allowed = %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestMemoryLimiterAllOrNothing(t *testing.T) {
+	l, _ := newTestLimiter()
+	user := Request{Key: "user:1", Limit: Every(1, time.Minute, 1)}
+	ip := Request{Key: "ip:1", Limit: Every(10, time.Minute, 10)}
+	news := Request{Key: "news:1", Limit: Every(10, time.Minute, 10)}
+
+	if !allow(t, l, user, ip, news).Allowed {
+		t.Fatal("first Allow() = false, want true")
+	}
+	// корзина пользователя пуста: токены IP и новости не списываются
+	for range 5 {
+		if allow(t, l, user, ip, news).Allowed {
+			t.Fatal("Allow() with empty user bucket = true, want false")
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		want float64
+	}{
+		{"user", user, 0},
+		{"ip", ip, 9},
+		{"news", news, 9},
+	}
+	for _, tt := range tests {
+		if got := l.buckets[tt.req.Key].tokens; got != tt.want {
+			t.Errorf("%s tokens = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryLimiterRetryAfter(t *testing.T) {
+	l, now := newTestLimiter()
+	slow := Request{Key: "user:1", Limit: Every(1, 10*time.Second, 1)}
+	fast := Request{Key: "ip:1", Limit: Every(1, time.Second, 1)}
+
+	allow(t, l, slow, fast)
+	*now = now.Add(4 * time.Second)
+
+	// Retry-After определяется самой медленной пустой корзиной
+	decision := allow(t, l, slow, fast)
+	if decision.Allowed {
+		t.Fatal("Allowed = true, want false")
+	}
+	if want := 6 * time.Second; decision.RetryAfter != want {
+		t.Errorf("RetryAfter = %v, want %v", decision.RetryAfter, want)
+	}
+
+	*now = now.Add(decision.RetryAfter)
+	if !allow(t, l, slow, fast).Allowed {
+		t.Error("Allowed after RetryAfter = false, want true")
+	}
+}
+
+func TestMemoryLimiterUnlimited(t *testing.T) {
+	l, _ := newTestLimiter()
+	for range 100 {
+		if !allow(t, l, Request{Key: "news:1"}).Allowed {
+			t.Fatal("Allow() with zero limit = false, want true")
+		}
+	}
+	if len(l.buckets) != 0 {
+		t.Errorf("buckets = %d, want none for unlimited requests", len(l.buckets))
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	l, now := newTestLimiter()
+	limit := Every(1, time.Second, 1)
+	allow(t, l, Request{Key: "user:1", Limit: limit})
+	*now = now.Add(500 * time.Millisecond)
+	allow(t, l, Request{Key: "user:2", Limit: limit})
+
+	// корзина user:1 уже полна, user:2 еще нет
+	l.sweep(now.Add(500 * time.Millisecond))
+	if _, ok := l.buckets["user:1"]; ok {
+		t.Error("full bucket user:1 was not swept")
+	}
+	if _, ok := l.buckets["user:2"]; !ok {
+		t.Error("partial bucket user:2 was swept")
+	}
+}
+
+func TestEvery(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests int
+		period   time.Duration
+		burst    int
+		want     Limit
+	}{
+		{"default burst", 60, time.Minute, 0, Limit{Rate: 1, Burst: 60}},
+		{"explicit burst", 60, time.Minute, 5, Limit{Rate: 1, Burst: 5}},
+		{"disabled", 0, time.Minute, 5, Limit{}},
+		{"no period", 10, 0, 5, Limit{}},
+	}
+	for _, tt := range tests {
+		if got := Every(tt.requests, tt.period, tt.burst); got != tt.want {
+			t.Errorf("%s: Every() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
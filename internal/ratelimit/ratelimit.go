@@ -0,0 +1,76 @@
+// Package ratelimit ограничивает частоту добавления комментариев по
+// алгоритму token bucket. Состояние хранится за интерфейсом Limiter, чтобы
+// реплики могли делить его через общее хранилище.
+package ratelimit
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"context"
+	"time"
+)
+
+// Limit емкость корзины Burst и скорость ее пополнения Rate (токенов в
+// секунду). Нулевой Limit не ограничивает запросы.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited сообщает, что лимит не задан
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Every возвращает лимит в requests запросов за period с емкостью burst;
+// burst <= 0 означает емкость requests
+func Every(requests int, period time.Duration, burst int) Limit {
+	if requests <= 0 || period <= 0 {
+		return Limit{}
+	}
+	if burst <= 0 {
+		burst = requests
+	}
+	return Limit{Rate: float64(requests) / period.Seconds(), Burst: burst}
+}
+
+// Request запрос одного токена из корзины Key с лимитом Limit
+type Request struct {
+	Key   string
+	Limit Limit
+}
+
+// Decision результат проверки. RetryAfter - через сколько запрос будет
+// разрешен, если Allowed == false.
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limiter хранит корзины и списывает токены. Токены списываются только если
+// разрешены все запросы сразу, иначе ни один.
+type Limiter interface {
+	Allow(ctx context.Context, requests ...Request) (Decision, error)
+}
+
+// Limits лимиты добавления комментариев по пользователю, IP и новости и
+// лимит правок Edit, действующий отдельно для пользователя и IP
+type Limits struct {
+	User Limit
+	IP   Limit
+	News Limit
+	Edit Limit
+}
+
+// NewLimits строит лимиты по настройкам cfg
+func NewLimits(cfg config.RateLimitConfig) Limits {
+	return Limits{
+		User: ruleLimit(cfg.User),
+		IP:   ruleLimit(cfg.IP),
+		News: ruleLimit(cfg.News),
+		Edit: ruleLimit(cfg.Edit),
+	}
+}
+
+func ruleLimit(rule config.RateLimitRule) Limit {
+	return Every(rule.Requests, time.Duration(rule.PeriodSeconds)*time.Second, rule.Burst)
+}
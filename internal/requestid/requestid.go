@@ -0,0 +1,87 @@
+// Package requestid хранит в контексте сквозной ID запроса, который
+// передается между сервисами в HTTP и Kafka заголовках и в моделях ответов.
+package requestid
+
+import (
+	"commentservice/internal/logging"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Header заголовок с ID запроса в HTTP запросах и сообщениях Kafka
+const Header = "X-Request-ID"
+
+// maxLength ограничивает длину принятого извне ID
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext возвращает контекст с ID запроса. ID добавляется и в атрибуты логов.
+func NewContext(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, contextKey{}, id)
+	return logging.WithAttrs(ctx, slog.String("request_id", id))
+}
+
+// FromContext извлекает ID запроса из контекста
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// SetHeader передает ID запроса из ctx в заголовки исходящего HTTP запроса
+func SetHeader(ctx context.Context, header http.Header) {
+	if id := FromContext(ctx); id != "" {
+		header.Set(Header, id)
+	}
+}
+
+// New генерирует новый ID запроса
+func New() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "fallback" + fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// Valid проверяет ID, пришедший извне: непустой, не длиннее maxLength и
+// только из букв, цифр и символов "-", "_", ".", ":"
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FromTraceparent возвращает trace-id из заголовка W3C traceparent
+// ("00-<trace-id>-<parent-id>-<flags>"), если заголовок корректен
+func FromTraceparent(header string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 ||
+		len(parts[2]) != 16 || len(parts[3]) != 2 || parts[0] == "ff" {
+		return "", false
+	}
+	for _, part := range parts {
+		if _, err := hex.DecodeString(part); err != nil || strings.ToLower(part) != part {
+			return "", false
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -1,65 +1,775 @@
 package service
 
 import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/auth"
+	"commentservice/internal/censor"
+	"commentservice/internal/clientip"
+	"commentservice/internal/logging"
 	"commentservice/internal/models"
+	"commentservice/internal/moderation"
+	"commentservice/internal/ratelimit"
+	"commentservice/internal/requestid"
+	"commentservice/internal/tracing"
 	"commentservice/storage"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// defaultMaxThreadDepth используется, если максимальная глубина дерева не задана
+	defaultMaxThreadDepth = 5
+	// defaultCommentLimit используется, если размер страницы не задан ни в запросе, ни в конфигурации
+	defaultCommentLimit = 10
+	// maxCommentLimit максимальный размер страницы комментариев
+	maxCommentLimit = 100
+)
+
+// Причины отклонения комментария в метриках
+const (
+	rejectReasonInvalid      = "invalid"
+	rejectReasonAuth         = "unauthenticated"
+	rejectReasonRateLimited  = "rate_limited"
+	rejectReasonNewsNotFound = "news_not_found"
+	rejectReasonParent       = "invalid_parent"
+	rejectReasonFilter       = "filter"
+	rejectReasonCensor       = "censor"
+	rejectReasonError        = "error"
 )
 
+// ErrCommentCensored возвращается, если комментарий не прошел цензуру и
+// политика требует его отклонить
+var ErrCommentCensored = apperr.New(apperr.ErrCensored, "comment rejected by censorship")
+
+// ErrAuthenticationRequired возвращается при записи без субъекта запроса,
+// если включена авторизация
+var ErrAuthenticationRequired = apperr.New(apperr.ErrUnauthenticated, "authentication required")
+
+// ErrNotCommentAuthor возвращается при изменении чужого комментария
+var ErrNotCommentAuthor = apperr.New(apperr.ErrForbidden, "only the author or a moderator can change the comment")
+
 type CommentServiceImpl struct {
 	commentsStorage storage.CommentsStorage
 	newsStorage     storage.NewsStorage
 	log             *slog.Logger
+	maxThreadDepth  int
+	defaultLimit    int
+	censor          Censor
+	censorPolicy    censor.Policy
+	censorFailOpen  bool
+	filter          ContentFilter
+	metrics         Metrics
+	events          EventPublisher
+	authorize       bool
+	moderatorRole   string
+	limiter         ratelimit.Limiter
+	limits          ratelimit.Limits
+}
+
+// Option настраивает CommentServiceImpl
+type Option func(*CommentServiceImpl)
+
+// WithMaxThreadDepth задает максимальную глубину дерева ответов
+func WithMaxThreadDepth(depth int) Option {
+	return func(s *CommentServiceImpl) {
+		if depth > 0 {
+			s.maxThreadDepth = depth
+		}
+	}
+}
+
+// WithDefaultLimit задает размер страницы комментариев по умолчанию
+func WithDefaultLimit(limit int) Option {
+	return func(s *CommentServiceImpl) {
+		if limit > 0 {
+			s.defaultLimit = min(limit, maxCommentLimit)
+		}
+	}
 }
 
+// WithCensor включает проверку новых комментариев сервисом цензуры.
+// failOpen разрешает сохранять комментарии, если проверка не удалась.
+func WithCensor(c Censor, policy censor.Policy, failOpen bool) Option {
+	return func(s *CommentServiceImpl) {
+		s.censor = c
+		s.censorPolicy = policy
+		s.censorFailOpen = failOpen
+	}
+}
+
+// WithFilter включает локальную проверку комментариев по словарям.
+// Фильтр применяется до обращения к сервису цензуры.
+func WithFilter(f ContentFilter) Option {
+	return func(s *CommentServiceImpl) {
+		s.filter = f
+	}
+}
+
+// WithMetrics включает учет добавленных и отклоненных комментариев
+func WithMetrics(m Metrics) Option {
+	return func(s *CommentServiceImpl) {
+		s.metrics = m
+	}
+}
+
+// WithEventPublisher включает публикацию событий о добавлении, изменении и
+// удалении комментариев
+func WithEventPublisher(p EventPublisher) Option {
+	return func(s *CommentServiceImpl) {
+		s.events = p
+	}
+}
+
+// WithAuthorization требует субъекта запроса (auth.Principal) для записи.
+// Автором комментария становится пользователь из токена; изменять и удалять
+// комментарий могут только автор и пользователи с ролью moderatorRole.
+func WithAuthorization(moderatorRole string) Option {
+	return func(s *CommentServiceImpl) {
+		s.authorize = true
+		s.moderatorRole = moderatorRole
+	}
+}
+
+// WithRateLimit ограничивает частоту добавления комментариев от одного
+// пользователя, с одного IP адреса и к одной новости, а также частоту правок
+func WithRateLimit(limiter ratelimit.Limiter, limits ratelimit.Limits) Option {
+	return func(s *CommentServiceImpl) {
+		s.limiter = limiter
+		s.limits = limits
+	}
+}
+
+// noopMetrics используется, если метрики не включены
+type noopMetrics struct{}
+
+func (noopMetrics) CommentAdded(bool)      {}
+func (noopMetrics) CommentRejected(string) {}
+
 func NewCommentService(
 	commentsStorage storage.CommentsStorage,
 	newsStorage storage.NewsStorage,
 	log *slog.Logger,
+	opts ...Option,
 ) CommentService {
-	return &CommentServiceImpl{
+	s := &CommentServiceImpl{
 		commentsStorage: commentsStorage,
 		newsStorage:     newsStorage,
 		log:             log,
+		maxThreadDepth:  defaultMaxThreadDepth,
+		defaultLimit:    defaultCommentLimit,
+		metrics:         noopMetrics{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *CommentServiceImpl) AddComment(ctx context.Context, newsID int, comment string) error {
+func (s *CommentServiceImpl) AddComment(ctx context.Context, comment models.Comment) (_ models.Comment, err error) {
+	ctx, end := startSpan(ctx, "AddComment", attribute.Int("news_id", comment.NewsID))
+	defer end(&err)
+
+	saved, reason, err := s.addComment(ctx, comment)
+	if err != nil {
+		s.metrics.CommentRejected(reason)
+		return models.Comment{}, err
+	}
+	s.metrics.CommentAdded(saved.Cens)
+	s.publish(ctx, models.EventCommentCreated, saved, false)
+	return saved, nil
+}
+
+// addComment проверяет и сохраняет комментарий. При ошибке возвращает
+// причину отклонения для метрик.
+func (s *CommentServiceImpl) addComment(ctx context.Context, comment models.Comment) (models.Comment, string, error) {
+	newsID := comment.NewsID
+	if err := s.setAuthor(ctx, &comment); err != nil {
+		if errors.Is(err, apperr.ErrUnauthenticated) {
+			return models.Comment{}, rejectReasonAuth, err
+		}
+		return models.Comment{}, rejectReasonInvalid, err
+	}
+	if strings.TrimSpace(comment.Content) == "" {
+		return models.Comment{}, rejectReasonInvalid, apperr.Invalid("content", "must not be empty")
+	}
+	if err := storage.ValidateContent(comment.Content); err != nil {
+		return models.Comment{}, rejectReasonInvalid, err
+	}
+	// Лимит проверяется до обращений к хранилищу, фильтру и сервису цензуры,
+	// чтобы поток запросов сверх лимита не создавал на них нагрузку
+	if err := s.checkRateLimit(ctx, comment); err != nil {
+		return models.Comment{}, rejectReasonRateLimited, err
+	}
 	exists, err := s.newsStorage.NewsExists(ctx, newsID)
 	if err != nil {
-		s.log.Error("failed to check news existence", "news_id", newsID, "error", err)
-		return fmt.Errorf("failed to check news existence: %w", err)
+		s.log.ErrorContext(ctx, "failed to check news existence", "error", err)
+		return models.Comment{}, rejectReasonError, fmt.Errorf("failed to check news existence: %w", err)
 	}
 	if !exists {
-		s.log.Warn("news not found", "news_id", newsID)
-		return fmt.Errorf("news with id %d not found", newsID)
+		s.log.WarnContext(ctx, "news not found")
+		return models.Comment{}, rejectReasonNewsNotFound, apperr.NotFound("news", newsID)
+	}
+
+	if comment.ParentID != nil {
+		parent, err := s.commentsStorage.GetComment(ctx, *comment.ParentID)
+		if errors.Is(err, storage.ErrCommentNotFound) {
+			s.log.WarnContext(ctx, "parent comment not found", "parent_id", *comment.ParentID)
+			return models.Comment{}, rejectReasonParent, apperr.Invalid("parent_id", "comment %d not found", *comment.ParentID)
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to get parent comment", "parent_id", *comment.ParentID, "error", err)
+			return models.Comment{}, rejectReasonError, fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		if parent.Deleted {
+			return models.Comment{}, rejectReasonParent, fmt.Errorf("cannot reply to deleted comment %d: %w", parent.CommentID, storage.ErrCommentDeleted)
+		}
+		if parent.NewsID != newsID {
+			s.log.WarnContext(ctx, "parent comment belongs to another news",
+				"news_id", newsID, "parent_id", parent.CommentID, "parent_news_id", parent.NewsID)
+			return models.Comment{}, rejectReasonParent, apperr.Invalid("parent_id", "comment %d belongs to news %d", parent.CommentID, parent.NewsID)
+		}
 	}
 
-	if err := s.commentsStorage.AddComment(ctx, newsID, comment); err != nil {
-		s.log.Error("failed to save comment", "news_id", newsID, "error", err)
-		return fmt.Errorf("failed to save comment: %w", err)
+	if reason, err := s.moderate(ctx, &comment); err != nil {
+		return models.Comment{}, reason, err
+	}
+
+	saved, err := s.commentsStorage.AddComment(ctx, comment)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to save comment", "error", err)
+		return models.Comment{}, rejectReasonError, fmt.Errorf("failed to save comment: %w", err)
 	}
 
-	s.log.Info("comment added successfully", "news_id", newsID)
+	s.log.InfoContext(ctx, "comment added successfully", "comment_id", saved.CommentID)
+	return saved, "", nil
+}
+
+// GetComments возвращает страницу комментариев новости. Если лимит не задан,
+// используется лимит по умолчанию; слишком большой лимит урезается до максимума.
+func (s *CommentServiceImpl) GetComments(ctx context.Context, query models.CommentQuery) (_ models.CommentPage, err error) {
+	ctx, end := startSpan(ctx, "GetComments", attribute.Int("news_id", query.NewsID))
+	defer end(&err)
+
+	newsID := query.NewsID
+	exists, err := s.newsStorage.NewsExists(ctx, newsID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to check news existance in database", "error", err)
+		return models.CommentPage{}, err
+	}
+	if !exists {
+		return models.CommentPage{}, apperr.NotFound("news", newsID)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = s.defaultLimit
+	}
+	query.Limit = min(query.Limit, maxCommentLimit)
+	if query.Offset < 0 {
+		return models.CommentPage{}, apperr.Invalid("offset", "must not be negative, got %d", query.Offset)
+	}
+	if !models.ValidSort(query.Sort) {
+		return models.CommentPage{}, apperr.Invalid("sort", "unknown sort order %q", query.Sort)
+	}
+
+	page, err := s.commentsStorage.GetComments(ctx, query)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comments from database", "error", err)
+		return models.CommentPage{}, err
+	}
+
+	if query.WithReactions {
+		if err := s.attachReactions(ctx, page.Comments); err != nil {
+			return models.CommentPage{}, err
+		}
+	}
+	return page, nil
+}
+
+// attachReactions заполняет сводки реакций комментариев страницы
+func (s *CommentServiceImpl) attachReactions(ctx context.Context, comments []models.Comment) error {
+	commentIDs := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.CommentID)
+	}
+	summaries, err := s.commentsStorage.GetReactionSummaries(ctx, commentIDs, viewerID(ctx))
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get reaction summaries", "error", err)
+		return fmt.Errorf("failed to get reaction summaries: %w", err)
+	}
+	for i := range comments {
+		summary := summaries[comments[i].CommentID]
+		comments[i].Reactions = &summary
+	}
 	return nil
 }
 
-func (s *CommentServiceImpl) GetComments(ctx context.Context, newsID int) ([]models.Comment, error) {
+// GetCommentThread возвращает дерево комментариев новости для страницы корневых
+// комментариев. Глубина ограничивается query.MaxDepth, но не больше настроенного
+// максимума; MaxDepth <= 0 означает максимум.
+func (s *CommentServiceImpl) GetCommentThread(ctx context.Context, query models.ThreadQuery) (_ []*models.CommentNode, _ int, err error) {
+	ctx, end := startSpan(ctx, "GetCommentThread", attribute.Int("news_id", query.NewsID))
+	defer end(&err)
+
+	newsID := query.NewsID
 	exists, err := s.newsStorage.NewsExists(ctx, newsID)
 	if err != nil {
-		s.log.Error("failed to check news existance in database")
-		return nil, err
+		s.log.ErrorContext(ctx, "failed to check news existance in database", "error", err)
+		return nil, 0, err
 	}
 	if !exists {
-		return nil, fmt.Errorf("news with id %d not found", newsID)
+		return nil, 0, apperr.NotFound("news", newsID)
+	}
+
+	if query.MaxDepth <= 0 || query.MaxDepth > s.maxThreadDepth {
+		query.MaxDepth = s.maxThreadDepth
+	}
+	if query.Offset < 0 {
+		return nil, 0, apperr.Invalid("offset", "must not be negative, got %d", query.Offset)
+	}
+
+	nodes, total, err := s.commentsStorage.GetCommentThread(ctx, query)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment thread from database", "error", err)
+		return nil, 0, err
+	}
+
+	return buildCommentTree(nodes), total, nil
+}
+
+// moderate проверяет комментарий локальным фильтром и сервисом цензуры.
+// Если фильтр уже отметил комментарий, сетевая проверка не выполняется.
+// При отклонении возвращает причину для метрик.
+func (s *CommentServiceImpl) moderate(ctx context.Context, comment *models.Comment) (string, error) {
+	comment.Cens, comment.CensReason = false, ""
+
+	if err := s.applyFilter(ctx, comment); err != nil {
+		return rejectReasonFilter, err
 	}
-	comments, err := s.commentsStorage.GetComments(ctx, newsID)
+	if comment.Cens {
+		return "", nil
+	}
+	if err := s.applyCensor(ctx, comment); err != nil {
+		if errors.Is(err, ErrCommentCensored) {
+			return rejectReasonCensor, err
+		}
+		return rejectReasonError, err
+	}
+	return "", nil
+}
+
+// applyFilter проверяет комментарий локальным фильтром по словарям
+func (s *CommentServiceImpl) applyFilter(ctx context.Context, comment *models.Comment) error {
+	if s.filter == nil {
+		return nil
+	}
+
+	result := s.filter.Check(comment.Content)
+	switch result.Action {
+	case moderation.ActionReject:
+		s.log.InfoContext(ctx, "comment rejected by filter", "rule", result.Rule)
+		return fmt.Errorf("%w: %s", ErrCommentCensored, result.Rule)
+	case moderation.ActionFlag:
+		s.log.InfoContext(ctx, "comment flagged by filter", "rule", result.Rule)
+		comment.Cens = true
+		comment.CensReason = result.Rule
+	}
+	return nil
+}
+
+// applyCensor проверяет комментарий сервисом цензуры и, в зависимости от
+// политики, отклоняет его или выставляет признак Cens
+func (s *CommentServiceImpl) applyCensor(ctx context.Context, comment *models.Comment) error {
+	if s.censor == nil {
+		return nil
+	}
+
+	verdict, err := s.censor.Check(ctx, comment.Content)
 	if err != nil {
-		s.log.Error("failed to get comments from database")
-		return nil, err
+		if s.censorFailOpen {
+			s.log.WarnContext(ctx, "censor check failed, accepting comment", "error", err)
+			return nil
+		}
+		s.log.ErrorContext(ctx, "censor check failed", "error", err)
+		return fmt.Errorf("failed to check comment: %w", err)
+	}
+	if !verdict.Censored {
+		return nil
+	}
+
+	if s.censorPolicy == censor.PolicyFlag {
+		s.log.InfoContext(ctx, "comment flagged by censor", "reason", verdict.Reason)
+		comment.Cens = true
+		comment.CensReason = "censorservice"
+		if verdict.Reason != "" {
+			comment.CensReason += ": " + verdict.Reason
+		}
+		return nil
+	}
+
+	s.log.InfoContext(ctx, "comment rejected by censor", "reason", verdict.Reason)
+	if verdict.Reason != "" {
+		return fmt.Errorf("%w: %s", ErrCommentCensored, verdict.Reason)
+	}
+	return ErrCommentCensored
+}
+
+// UpdateComment изменяет текст комментария. Предыдущий текст сохраняется в истории правок.
+// Новый текст проходит ту же модерацию, что и при добавлении.
+func (s *CommentServiceImpl) UpdateComment(ctx context.Context, commentID int, content string) (_ models.Comment, err error) {
+	ctx, end := startSpan(ctx, "UpdateComment", attribute.Int("comment_id", commentID))
+	defer end(&err)
+
+	if strings.TrimSpace(content) == "" {
+		return models.Comment{}, apperr.Invalid("content", "must not be empty")
+	}
+	if err := storage.ValidateContent(content); err != nil {
+		return models.Comment{}, err
+	}
+	if err := s.checkEditRateLimit(ctx); err != nil {
+		return models.Comment{}, err
+	}
+	if err := s.checkAuthor(ctx, commentID); err != nil {
+		return models.Comment{}, err
+	}
+
+	comment := models.Comment{CommentID: commentID, Content: content}
+	if _, err := s.moderate(ctx, &comment); err != nil {
+		return models.Comment{}, err
+	}
+
+	updated, wasCens, err := s.commentsStorage.UpdateComment(ctx, comment)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to update comment", "error", err)
+		return models.Comment{}, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	s.log.InfoContext(ctx, "comment updated successfully", "news_id", updated.NewsID)
+	s.publish(ctx, models.EventCommentUpdated, updated, wasCens)
+	return updated, nil
+}
+
+// DeleteComment мягко удаляет комментарий, оставляя ответы на него в ветке
+func (s *CommentServiceImpl) DeleteComment(ctx context.Context, commentID int) (err error) {
+	ctx, end := startSpan(ctx, "DeleteComment", attribute.Int("comment_id", commentID))
+	defer end(&err)
+
+	if err := s.checkAuthor(ctx, commentID); err != nil {
+		return err
+	}
+	deleted, err := s.commentsStorage.DeleteComment(ctx, commentID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to delete comment", "error", err)
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if !deleted {
+		// Повторное удаление: событие уже отправлено первым вызовом
+		return nil
+	}
+
+	s.log.InfoContext(ctx, "comment deleted successfully")
+	if s.events != nil {
+		// Для события нужна новость удаленного комментария
+		comment, err := s.commentsStorage.GetComment(ctx, commentID)
+		if err != nil {
+			s.log.WarnContext(ctx, "failed to load deleted comment for event", "error", err)
+			return nil
+		}
+		s.publish(ctx, models.EventCommentDeleted, comment, false)
+	}
+	return nil
+}
+
+// GetComment возвращает комментарий по ID
+func (s *CommentServiceImpl) GetComment(ctx context.Context, commentID int) (_ models.Comment, err error) {
+	ctx, end := startSpan(ctx, "GetComment", attribute.Int("comment_id", commentID))
+	defer end(&err)
+
+	comment, err := s.commentsStorage.GetComment(ctx, commentID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment", "error", err)
+		return models.Comment{}, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// GetCommentsByIDs возвращает найденные комментарии по ID. Отсутствующие ID
+// в результат не попадают и ошибкой не считаются.
+func (s *CommentServiceImpl) GetCommentsByIDs(ctx context.Context, commentIDs []int) (_ map[int]models.Comment, err error) {
+	ctx, end := startSpan(ctx, "GetCommentsByIDs", attribute.Int("comment_count", len(commentIDs)))
+	defer end(&err)
+
+	comments, err := s.commentsStorage.GetCommentsByIDs(ctx, commentIDs)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comments by ids", "error", err)
+		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
 
 	return comments, nil
 }
+
+// GetCommentHistory возвращает предыдущие версии текста комментария
+func (s *CommentServiceImpl) GetCommentHistory(ctx context.Context, commentID int) (_ []models.CommentRevision, err error) {
+	ctx, end := startSpan(ctx, "GetCommentHistory", attribute.Int("comment_id", commentID))
+	defer end(&err)
+
+	revisions, err := s.commentsStorage.GetCommentRevisions(ctx, commentID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment history", "error", err)
+		return nil, fmt.Errorf("failed to get comment history: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetCommentHistories возвращает истории правок нескольких комментариев.
+// Отсутствующие ID в результат не попадают и ошибкой не считаются.
+func (s *CommentServiceImpl) GetCommentHistories(ctx context.Context, commentIDs []int) (_ map[int][]models.CommentRevision, err error) {
+	ctx, end := startSpan(ctx, "GetCommentHistories", attribute.Int("comment_count", len(commentIDs)))
+	defer end(&err)
+
+	histories, err := s.commentsStorage.GetCommentRevisionsByIDs(ctx, commentIDs)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment histories", "error", err)
+		return nil, fmt.Errorf("failed to get comment histories: %w", err)
+	}
+
+	return histories, nil
+}
+
+// SetReaction ставит реакцию пользователя на комментарий, заменяя прежнюю
+func (s *CommentServiceImpl) SetReaction(ctx context.Context, commentID int, reaction string) (_ models.ReactionSummary, err error) {
+	ctx, end := startSpan(ctx, "SetReaction", attribute.Int("comment_id", commentID))
+	defer end(&err)
+
+	if !models.ValidReaction(reaction) {
+		return models.ReactionSummary{}, apperr.Invalid("reaction", "must be %q or %q, got %q", models.ReactionUp, models.ReactionDown, reaction)
+	}
+	userID := viewerID(ctx)
+	if userID == "" {
+		return models.ReactionSummary{}, ErrAuthenticationRequired
+	}
+
+	summary, err := s.commentsStorage.SetReaction(ctx, commentID, userID, reaction)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to set reaction", "error", err)
+		return models.ReactionSummary{}, fmt.Errorf("failed to set reaction: %w", err)
+	}
+	return summary, nil
+}
+
+// RemoveReaction снимает реакцию пользователя с комментария
+func (s *CommentServiceImpl) RemoveReaction(ctx context.Context, commentID int) (_ models.ReactionSummary, err error) {
+	ctx, end := startSpan(ctx, "RemoveReaction", attribute.Int("comment_id", commentID))
+	defer end(&err)
+
+	userID := viewerID(ctx)
+	if userID == "" {
+		return models.ReactionSummary{}, ErrAuthenticationRequired
+	}
+
+	summary, err := s.commentsStorage.RemoveReaction(ctx, commentID, userID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to remove reaction", "error", err)
+		return models.ReactionSummary{}, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	return summary, nil
+}
+
+// GetReactionSummaries возвращает сводки реакций комментариев с реакцией
+// пользователя из контекста
+func (s *CommentServiceImpl) GetReactionSummaries(ctx context.Context, commentIDs []int) (_ map[int]models.ReactionSummary, err error) {
+	ctx, end := startSpan(ctx, "GetReactionSummaries", attribute.Int("comment_count", len(commentIDs)))
+	defer end(&err)
+
+	summaries, err := s.commentsStorage.GetReactionSummaries(ctx, commentIDs, viewerID(ctx))
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get reaction summaries", "error", err)
+		return nil, fmt.Errorf("failed to get reaction summaries: %w", err)
+	}
+	return summaries, nil
+}
+
+// viewerID возвращает ID пользователя из контекста; пусто для анонимных
+// запросов и запросов сервисов. Голосовать могут только пользователи.
+func viewerID(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok && p.Kind == auth.KindUser {
+		return p.ID
+	}
+	return ""
+}
+
+// setAuthor заполняет автора комментария по субъекту запроса. Пользователь
+// всегда становится автором сам; сервис, обратившийся с API ключом,
+// передает автора в самом комментарии. Без авторизации автор сохраняется
+// в том виде, в котором пришел.
+func (s *CommentServiceImpl) setAuthor(ctx context.Context, comment *models.Comment) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		if s.authorize {
+			return ErrAuthenticationRequired
+		}
+		return nil
+	}
+
+	switch p.Kind {
+	case auth.KindUser:
+		comment.AuthorID, comment.AuthorName = p.ID, p.Name
+	case auth.KindService:
+		if comment.AuthorID == "" {
+			return apperr.Invalid("author_id", "is required for requests from service %s", p.ID)
+		}
+	}
+	return nil
+}
+
+// checkAuthor проверяет, что субъект запроса может изменять комментарий:
+// он его автор или модератор
+func (s *CommentServiceImpl) checkAuthor(ctx context.Context, commentID int) error {
+	if !s.authorize {
+		return nil
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return ErrAuthenticationRequired
+	}
+	if p.HasRole(s.moderatorRole) {
+		return nil
+	}
+
+	comment, err := s.commentsStorage.GetComment(ctx, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to get comment: %w", err)
+	}
+	if p.Kind != auth.KindUser || comment.AuthorID == "" || comment.AuthorID != p.ID {
+		s.log.WarnContext(ctx, "comment change denied", "principal", p.ID, "author_id", comment.AuthorID)
+		return ErrNotCommentAuthor
+	}
+	return nil
+}
+
+// checkRateLimit списывает токены из корзин автора, IP адреса клиента и
+// новости. Автор и IP учитываются, если известны.
+func (s *CommentServiceImpl) checkRateLimit(ctx context.Context, comment models.Comment) error {
+	if s.limiter == nil {
+		return nil
+	}
+
+	requests := []ratelimit.Request{{Key: "news:" + strconv.Itoa(comment.NewsID), Limit: s.limits.News}}
+	if comment.AuthorID != "" {
+		requests = append(requests, ratelimit.Request{Key: "user:" + comment.AuthorID, Limit: s.limits.User})
+	}
+	if ip := clientip.FromContext(ctx); ip != "" {
+		requests = append(requests, ratelimit.Request{Key: "ip:" + ip, Limit: s.limits.IP})
+	}
+	return s.allow(ctx, requests)
+}
+
+// checkEditRateLimit списывает токены правок из корзин субъекта запроса и IP
+// адреса клиента. Правки проходят ту же модерацию, что и новые комментарии,
+// поэтому ограничиваются отдельным лимитом.
+func (s *CommentServiceImpl) checkEditRateLimit(ctx context.Context) error {
+	if s.limiter == nil {
+		return nil
+	}
+
+	var requests []ratelimit.Request
+	if p, ok := auth.FromContext(ctx); ok {
+		requests = append(requests, ratelimit.Request{Key: "edit:user:" + p.ID, Limit: s.limits.Edit})
+	}
+	if ip := clientip.FromContext(ctx); ip != "" {
+		requests = append(requests, ratelimit.Request{Key: "edit:ip:" + ip, Limit: s.limits.Edit})
+	}
+	return s.allow(ctx, requests)
+}
+
+// allow списывает токены запросов. Ошибка хранилища лимитов не мешает
+// изменению комментария.
+func (s *CommentServiceImpl) allow(ctx context.Context, requests []ratelimit.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	decision, err := s.limiter.Allow(ctx, requests...)
+	if err != nil {
+		s.log.WarnContext(ctx, "rate limit check failed", "error", err)
+		return nil
+	}
+	if !decision.Allowed {
+		s.log.WarnContext(ctx, "comment rate limit exceeded", "retry_after", decision.RetryAfter)
+		return &apperr.RateLimitError{RetryAfter: decision.RetryAfter}
+	}
+	return nil
+}
+
+// publish отправляет событие об изменении комментария. Если признак цензуры
+// выставлен этим изменением (wasCens - значение до него), дополнительно
+// отправляется comment.censored, как и при записи событий в outbox.
+func (s *CommentServiceImpl) publish(ctx context.Context, eventType string, comment models.Comment, wasCens bool) {
+	if s.events == nil {
+		return
+	}
+
+	event := models.CommentEvent{
+		Type:       eventType,
+		NewsID:     comment.NewsID,
+		CommentID:  comment.CommentID,
+		Comment:    &comment,
+		RequestID:  requestid.FromContext(ctx),
+		OccurredAt: time.Now(),
+	}
+	s.events.Publish(ctx, event)
+	if comment.Cens && !wasCens && eventType != models.EventCommentDeleted {
+		event.Type = models.EventCommentCensored
+		s.events.Publish(ctx, event)
+	}
+}
+
+// startSpan начинает спан метода сервиса и добавляет attrs в контекст логов.
+// Возвращаемая функция завершает спан, отмечая ошибку метода, и вызывается
+// через defer с адресом err.
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, func(*error)) {
+	ctx, span := tracing.Tracer().Start(ctx, "CommentService."+method, trace.WithAttributes(attrs...))
+
+	logAttrs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		logAttrs[i] = slog.Any(string(attr.Key), attr.Value.AsInterface())
+	}
+	ctx = logging.WithAttrs(ctx, logAttrs...)
+
+	return ctx, func(err *error) {
+		tracing.RecordError(span, *err)
+		span.End()
+	}
+}
+
+// buildCommentTree собирает дерево из плоского списка узлов, упорядоченного
+// так, что родитель всегда идет раньше своих ответов.
+func buildCommentTree(nodes []models.CommentNode) []*models.CommentNode {
+	byID := make(map[int]*models.CommentNode, len(nodes))
+	roots := make([]*models.CommentNode, 0)
+
+	for i := range nodes {
+		node := &nodes[i]
+		node.Replies = make([]*models.CommentNode, 0)
+		byID[node.CommentID] = node
+
+		if node.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := byID[*node.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	return roots
+}
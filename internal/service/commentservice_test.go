@@ -0,0 +1,124 @@
+package service
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/censor"
+	"commentservice/internal/clientip"
+	"commentservice/internal/models"
+	"commentservice/internal/ratelimit"
+	"commentservice/storage"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+const testNewsID = 1
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestService создает сервис поверх хранилищ в памяти с одной новостью testNewsID
+func newTestService(opts ...Option) CommentService {
+	log := testLogger()
+	return NewCommentService(storage.NewMemoryStorage(log), storage.NewMemoryNewsStorage(testNewsID), log, opts...)
+}
+
+// stubCensor отвечает заданным вердиктом или ошибкой
+type stubCensor struct {
+	verdict censor.Verdict
+	err     error
+}
+
+func (c stubCensor) Check(context.Context, string) (censor.Verdict, error) {
+	return c.verdict, c.err
+}
+
+func TestAddCommentCensor(t *testing.T) {
+	banned := censor.Verdict{Censored: true, Reason: "banned word: spam"}
+	unavailable := errors.New("censor service unavailable")
+
+	tests := []struct {
+		name       string
+		censor     stubCensor
+		policy     censor.Policy
+		failOpen   bool
+		wantErr    error
+		wantCens   bool
+		wantReason string
+	}{
+		{name: "clean", policy: censor.PolicyReject},
+		{name: "reject", censor: stubCensor{verdict: banned}, policy: censor.PolicyReject, wantErr: apperr.ErrCensored},
+		{name: "flag", censor: stubCensor{verdict: banned}, policy: censor.PolicyFlag, wantCens: true, wantReason: "censorservice: banned word: spam"},
+		{name: "flag without reason", censor: stubCensor{verdict: censor.Verdict{Censored: true}}, policy: censor.PolicyFlag, wantCens: true, wantReason: "censorservice"},
+		{name: "fail open", censor: stubCensor{err: unavailable}, policy: censor.PolicyReject, failOpen: true},
+		{name: "fail closed", censor: stubCensor{err: unavailable}, policy: censor.PolicyFlag, wantErr: unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(WithCensor(tt.censor, tt.policy, tt.failOpen))
+
+			saved, err := svc.AddComment(context.Background(), models.Comment{NewsID: testNewsID, Content: "buy spam"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("AddComment() error = %v, want %v", err, tt.wantErr)
+				}
+				page, err := svc.GetComments(context.Background(), models.CommentQuery{NewsID: testNewsID})
+				if err != nil {
+					t.Fatalf("GetComments() error = %v", err)
+				}
+				if len(page.Comments) != 0 {
+					t.Fatalf("rejected comment saved: %+v", page.Comments)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddComment() error = %v", err)
+			}
+			if saved.Cens != tt.wantCens || saved.CensReason != tt.wantReason {
+				t.Fatalf("Cens, CensReason = %v, %q, want %v, %q", saved.Cens, saved.CensReason, tt.wantCens, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAddCommentRateLimit(t *testing.T) {
+	limits := ratelimit.Limits{
+		User: ratelimit.Every(1, time.Minute, 1),
+		IP:   ratelimit.Every(1, time.Minute, 1),
+		News: ratelimit.Every(100, time.Minute, 100),
+	}
+	svc := newTestService(WithRateLimit(ratelimit.NewMemoryLimiter(), limits))
+	ctx := clientip.NewContext(context.Background(), "192.0.2.1")
+
+	if _, err := svc.AddComment(ctx, models.Comment{NewsID: testNewsID, Content: "first"}); err != nil {
+		t.Fatalf("first AddComment() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		comment models.Comment
+	}{
+		{name: "same news", comment: models.Comment{NewsID: testNewsID, Content: "second"}},
+		// лимит проверяется раньше существования новости
+		{name: "unknown news", comment: models.Comment{NewsID: 404, Content: "second"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.AddComment(ctx, tt.comment)
+			if !errors.Is(err, apperr.ErrRateLimited) {
+				t.Fatalf("AddComment() error = %v, want %v", err, apperr.ErrRateLimited)
+			}
+		})
+	}
+
+	// корзина другого IP не затронута
+	other := clientip.NewContext(context.Background(), "192.0.2.2")
+	if _, err := svc.AddComment(other, models.Comment{NewsID: testNewsID, Content: "third"}); err != nil {
+		t.Fatalf("AddComment() from another IP error = %v", err)
+	}
+}
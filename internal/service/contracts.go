@@ -1,11 +1,46 @@
 package service
 
 import (
+	"commentservice/internal/censor"
 	"commentservice/internal/models"
+	"commentservice/internal/moderation"
 	"context"
 )
 
 type CommentService interface {
-	AddComment(ctx context.Context, newsID int, comment string) error
-	GetComments(ctx context.Context, newsID int) ([]models.Comment, error)
+	AddComment(ctx context.Context, comment models.Comment) (models.Comment, error)
+	GetComment(ctx context.Context, commentID int) (models.Comment, error)
+	GetCommentsByIDs(ctx context.Context, commentIDs []int) (map[int]models.Comment, error)
+	GetComments(ctx context.Context, query models.CommentQuery) (models.CommentPage, error)
+	// GetCommentThread возвращает дерево для страницы корневых комментариев и
+	// общее число корневых комментариев новости
+	GetCommentThread(ctx context.Context, query models.ThreadQuery) ([]*models.CommentNode, int, error)
+	UpdateComment(ctx context.Context, commentID int, content string) (models.Comment, error)
+	DeleteComment(ctx context.Context, commentID int) error
+	GetCommentHistory(ctx context.Context, commentID int) ([]models.CommentRevision, error)
+	GetCommentHistories(ctx context.Context, commentIDs []int) (map[int][]models.CommentRevision, error)
+	SetReaction(ctx context.Context, commentID int, reaction string) (models.ReactionSummary, error)
+	RemoveReaction(ctx context.Context, commentID int) (models.ReactionSummary, error)
+	GetReactionSummaries(ctx context.Context, commentIDs []int) (map[int]models.ReactionSummary, error)
+}
+
+// Censor проверяет текст комментария на допустимость
+type Censor interface {
+	Check(ctx context.Context, content string) (censor.Verdict, error)
+}
+
+// ContentFilter локально проверяет текст комментария по словарям
+type ContentFilter interface {
+	Check(content string) moderation.Result
+}
+
+// Metrics учитывает результаты добавления комментариев
+type Metrics interface {
+	CommentAdded(censored bool)
+	CommentRejected(reason string)
+}
+
+// EventPublisher получает события изменений комментариев, например для потоковой выдачи подписчикам
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.CommentEvent)
 }
@@ -0,0 +1,227 @@
+// Package stream раздает события комментариев подписчикам потоковой
+// выдачи (SSE и WebSocket) в пределах процесса.
+package stream
+
+import (
+	"commentservice/internal/models"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistory    = 100
+	defaultBufferSize = 64
+	// idleTopicTTL время, в течение которого хранится история новости без подписчиков
+	idleTopicTTL = 10 * time.Minute
+	// sweepInterval период очистки историй новостей без подписчиков
+	sweepInterval = time.Minute
+)
+
+// Event событие комментария с ID для возобновления по Last-Event-ID. ID
+// возрастают в пределах новости.
+type Event struct {
+	ID uint64
+	models.CommentEvent
+}
+
+// Broker хранит подписки и последние события каждой новости. События
+// приходят из CommentService (Publish) или из Kafka (PublishWithID), если
+// реплики синхронизируются через топик событий.
+type Broker struct {
+	history    int
+	bufferSize int
+
+	mu     sync.Mutex
+	seq    uint64
+	topics map[int]*topic
+	closed bool
+}
+
+// topic подписчики и последние события одной новости
+type topic struct {
+	events     []Event
+	subs       map[*Subscription]struct{}
+	lastActive time.Time
+}
+
+// Subscription подписка на события новости. Канал Events закрывается при
+// отмене подписки, остановке брокера или если подписчик не успевает читать
+// события; в последнем случае Lagged возвращает true и клиенту следует
+// переподключиться с Last-Event-ID.
+type Subscription struct {
+	Events <-chan Event
+
+	events chan Event
+	broker *Broker
+	newsID int
+	lagged bool
+}
+
+// NewBroker создает брокер. history - число последних событий новости,
+// доступных для возобновления; bufferSize - размер очереди подписчика.
+func NewBroker(history, bufferSize int) *Broker {
+	if history <= 0 {
+		history = defaultHistory
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	return &Broker{
+		history:    history,
+		bufferSize: bufferSize,
+		topics:     make(map[int]*topic),
+	}
+}
+
+// Publish рассылает событие, присваивая ему следующий ID. Реализует
+// service.EventPublisher.
+func (b *Broker) Publish(_ context.Context, event models.CommentEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	b.publish(Event{ID: b.seq, CommentEvent: event})
+}
+
+// PublishWithID рассылает событие с ID, присвоенным источником, например
+// смещением в Kafka. События с ID не больше уже разосланного отбрасываются,
+// поэтому повторная доставка не дублирует их у подписчиков.
+func (b *Broker) PublishWithID(event models.CommentEvent, id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t, ok := b.topics[event.NewsID]; ok && len(t.events) > 0 && t.events[len(t.events)-1].ID >= id {
+		return
+	}
+	b.publish(Event{ID: id, CommentEvent: event})
+}
+
+// publish вызывается под b.mu
+func (b *Broker) publish(event Event) {
+	if b.closed {
+		return
+	}
+	t := b.topic(event.NewsID)
+	t.events = append(t.events, event)
+	if len(t.events) > b.history {
+		t.events = t.events[len(t.events)-b.history:]
+	}
+	t.lastActive = time.Now()
+
+	for sub := range t.subs {
+		select {
+		case sub.events <- event:
+		default:
+			sub.lagged = true
+			b.remove(t, sub)
+		}
+	}
+}
+
+// Subscribe подписывает на события новости. Если lastEventID не 0, в ответ
+// возвращаются сохраненные события с большим ID, которые клиент пропустил.
+func (b *Broker) Subscribe(newsID int, lastEventID uint64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan Event, b.bufferSize)
+	sub := &Subscription{Events: events, events: events, broker: b, newsID: newsID}
+	if b.closed {
+		close(events)
+		return sub, nil
+	}
+
+	t := b.topic(newsID)
+	t.subs[sub] = struct{}{}
+	t.lastActive = time.Now()
+
+	var missed []Event
+	if lastEventID > 0 {
+		for _, event := range t.events {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+	return sub, missed
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	if t, ok := s.broker.topics[s.newsID]; ok {
+		s.broker.remove(t, s)
+	}
+}
+
+// Lagged сообщает, что подписка закрыта из-за переполнения очереди
+func (s *Subscription) Lagged() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	return s.lagged
+}
+
+// Run периодически удаляет истории новостей, у которых давно нет подписчиков
+// и событий. Работает до отмены ctx.
+func (b *Broker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			b.sweep(now)
+		}
+	}
+}
+
+func (b *Broker) sweep(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for newsID, t := range b.topics {
+		if len(t.subs) == 0 && now.Sub(t.lastActive) > idleTopicTTL {
+			delete(b.topics, newsID)
+		}
+	}
+}
+
+// Close закрывает все подписки и перестает принимать события. Вызывается при
+// остановке HTTP сервера, чтобы потоковые ответы завершились.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, t := range b.topics {
+		for sub := range t.subs {
+			b.remove(t, sub)
+		}
+	}
+}
+
+// topic возвращает подписки новости, создавая их при необходимости. Вызывается под b.mu.
+func (b *Broker) topic(newsID int) *topic {
+	t, ok := b.topics[newsID]
+	if !ok {
+		t = &topic{subs: make(map[*Subscription]struct{})}
+		b.topics[newsID] = t
+	}
+	return t
+}
+
+// remove отписывает sub и закрывает его канал. Вызывается под b.mu.
+func (b *Broker) remove(t *topic, sub *Subscription) {
+	if _, ok := t.subs[sub]; !ok {
+		return
+	}
+	delete(t.subs, sub)
+	close(sub.events)
+	t.lastActive = time.Now()
+}
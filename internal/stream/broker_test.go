@@ -0,0 +1,218 @@
+package stream
+
+import (
+	"commentservice/internal/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func event(newsID, commentID int) models.CommentEvent {
+	return models.CommentEvent{Type: models.EventCommentCreated, NewsID: newsID, CommentID: commentID}
+}
+
+// drain читает из канала все события, которые уже в нем лежат
+func drain(sub *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case e, ok := <-sub.Events:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func ids(events []Event) []uint64 {
+	out := make([]uint64, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func equalIDs(got, want []uint64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// closed сообщает, что канал подписки закрыт и пуст
+func closed(sub *Subscription) bool {
+	select {
+	case _, ok := <-sub.Events:
+		return !ok
+	default:
+		return false
+	}
+}
+
+func TestBrokerResume(t *testing.T) {
+	tests := []struct {
+		name        string
+		history     int
+		published   int
+		lastEventID uint64
+		want        []uint64
+	}{
+		{name: "new subscriber gets nothing", history: 10, published: 3, lastEventID: 0, want: nil},
+		{name: "missed events after gap", history: 10, published: 5, lastEventID: 2, want: []uint64{3, 4, 5}},
+		{name: "up to date", history: 10, published: 3, lastEventID: 3, want: nil},
+		// часть пропущенных событий вытеснена из истории: отдается то, что осталось
+		{name: "gap longer than history", history: 3, published: 6, lastEventID: 1, want: []uint64{4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker(tt.history, 16)
+			for i := 1; i <= tt.published; i++ {
+				b.Publish(context.Background(), event(1, i))
+			}
+			// события другой новости не попадают в возобновление
+			b.Publish(context.Background(), event(2, 100))
+
+			sub, missed := b.Subscribe(1, tt.lastEventID)
+			defer sub.Close()
+			if got := ids(missed); !equalIDs(got, tt.want) {
+				t.Fatalf("missed = %v, want %v", got, tt.want)
+			}
+			for _, e := range missed {
+				if e.NewsID != 1 {
+					t.Fatalf("missed event of news %d, want 1", e.NewsID)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerResumeWithSourceIDs(t *testing.T) {
+	b := NewBroker(10, 16)
+	b.PublishWithID(event(1, 1), 10)
+	b.PublishWithID(event(1, 2), 20)
+	// повторная доставка из топика не дублирует событие
+	b.PublishWithID(event(1, 2), 20)
+	b.PublishWithID(event(1, 3), 30)
+
+	sub, missed := b.Subscribe(1, 10)
+	defer sub.Close()
+	if got, want := ids(missed), []uint64{20, 30}; !equalIDs(got, want) {
+		t.Fatalf("missed = %v, want %v", got, want)
+	}
+
+	b.PublishWithID(event(1, 4), 25)
+	b.PublishWithID(event(1, 5), 40)
+	if got, want := ids(drain(sub)), []uint64{40}; !equalIDs(got, want) {
+		t.Fatalf("live events = %v, want %v", got, want)
+	}
+}
+
+func TestBrokerLagDisconnect(t *testing.T) {
+	b := NewBroker(10, 2)
+	slow, _ := b.Subscribe(1, 0)
+	fast, _ := b.Subscribe(1, 0)
+
+	b.Publish(context.Background(), event(1, 1))
+	b.Publish(context.Background(), event(1, 2))
+	if got := ids(drain(fast)); !equalIDs(got, []uint64{1, 2}) {
+		t.Fatalf("fast events = %v, want [1 2]", got)
+	}
+	// очередь slow заполнена, третье событие закрывает его подписку
+	b.Publish(context.Background(), event(1, 3))
+
+	if !slow.Lagged() {
+		t.Fatal("slow.Lagged() = false, want true")
+	}
+	if got := ids(drain(slow)); !equalIDs(got, []uint64{1, 2}) {
+		t.Fatalf("slow events = %v, want [1 2] before close", got)
+	}
+	if !closed(slow) {
+		t.Fatal("slow channel is open, want closed")
+	}
+	if fast.Lagged() {
+		t.Fatal("fast.Lagged() = true, want false")
+	}
+	if got := ids(drain(fast)); !equalIDs(got, []uint64{3}) {
+		t.Fatalf("fast events = %v, want [3]", got)
+	}
+
+	// переподключение с Last-Event-ID возвращает пропущенное событие
+	resumed, missed := b.Subscribe(1, 2)
+	defer resumed.Close()
+	if got := ids(missed); !equalIDs(got, []uint64{3}) {
+		t.Fatalf("missed after lag = %v, want [3]", got)
+	}
+	// Close после отключения по отставанию безопасен
+	slow.Close()
+	fast.Close()
+}
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	b := NewBroker(10, 16)
+	first, _ := b.Subscribe(1, 0)
+	second, _ := b.Subscribe(1, 0)
+
+	first.Close()
+	if !closed(first) {
+		t.Fatal("first channel is open after Close, want closed")
+	}
+	// повторный Close не паникует на закрытом канале
+	first.Close()
+	if first.Lagged() {
+		t.Fatal("first.Lagged() = true after Close, want false")
+	}
+
+	b.Publish(context.Background(), event(1, 1))
+	if got := ids(drain(second)); !equalIDs(got, []uint64{1}) {
+		t.Fatalf("second events = %v, want [1]", got)
+	}
+	if n := len(b.topics[1].subs); n != 1 {
+		t.Fatalf("subscribers = %d, want 1", n)
+	}
+
+	second.Close()
+	if n := len(b.topics[1].subs); n != 0 {
+		t.Fatalf("subscribers = %d after Close, want 0", n)
+	}
+
+	// история новости без подписчиков удаляется после idleTopicTTL
+	lastActive := b.topics[1].lastActive
+	b.sweep(lastActive.Add(idleTopicTTL / 2))
+	if _, ok := b.topics[1]; !ok {
+		t.Fatal("topic swept before idleTopicTTL")
+	}
+	b.sweep(lastActive.Add(idleTopicTTL + time.Second))
+	if _, ok := b.topics[1]; ok {
+		t.Fatal("idle topic not swept")
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker(10, 16)
+	sub, _ := b.Subscribe(1, 0)
+
+	b.Close()
+	if !closed(sub) {
+		t.Fatal("subscription open after broker Close, want closed")
+	}
+	sub.Close()
+
+	// после остановки новые подписки сразу закрыты, события не рассылаются
+	late, missed := b.Subscribe(1, 0)
+	if missed != nil || !closed(late) {
+		t.Fatalf("Subscribe after Close: missed = %v, closed = %v", missed, closed(late))
+	}
+	b.Publish(context.Background(), event(1, 1))
+	if got := len(b.topics[1].events); got != 0 {
+		t.Fatalf("events after Close = %d, want 0", got)
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/auth"
+	"commentservice/internal/logging"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// TokenVerifier проверяет токен доступа и возвращает его владельца
+type TokenVerifier interface {
+	Verify(token string) (auth.Principal, error)
+}
+
+// AuthMiddleware проверяет JWT из заголовка Authorization: Bearer и
+// добавляет пользователя в контекст запроса. Запрос без токена проходит
+// анонимно - права на действие проверяет сервис; запрос с неверным токеном
+// отклоняется с 401.
+func AuthMiddleware(verifier TokenVerifier, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				unauthorized(w, r, "authorization header must use the Bearer scheme")
+				return
+			}
+			principal, err := verifier.Verify(strings.TrimSpace(token))
+			if err != nil {
+				log.WarnContext(r.Context(), "invalid access token", "error", err)
+				unauthorized(w, r, "invalid access token")
+				return
+			}
+
+			ctx := auth.NewContext(r.Context(), principal)
+			ctx = logging.WithAttrs(ctx, slog.String("user_id", principal.ID))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// unauthorized отвечает 401 в формате application/problem+json, как и api
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	WriteProblem(w, r, detail, http.StatusUnauthorized, apperr.CodeUnauthenticated)
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/auth"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// verifierFunc позволяет задать TokenVerifier функцией
+type verifierFunc func(token string) (auth.Principal, error)
+
+func (f verifierFunc) Verify(token string) (auth.Principal, error) { return f(token) }
+
+func TestAuthMiddlewareRejectsInvalidToken(t *testing.T) {
+	verifier := verifierFunc(func(string) (auth.Principal, error) {
+		return auth.Principal{}, errors.New("bad signature")
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request with invalid token reached the handler")
+	})
+	handler := RequestIDMiddleware(AuthMiddleware(verifier, slog.New(slog.NewTextHandler(io.Discard, nil)))(next))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/news/1/comments", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	r.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", got)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got == "" {
+		t.Error("WWW-Authenticate header is missing")
+	}
+
+	var body problem
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	want := problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusUnauthorized),
+		Status:    http.StatusUnauthorized,
+		Detail:    "invalid access token",
+		Instance:  "/v1/news/1/comments",
+		Code:      apperr.CodeUnauthenticated,
+		RequestID: "req-1",
+	}
+	if body.Type != want.Type || body.Title != want.Title || body.Status != want.Status ||
+		body.Detail != want.Detail || body.Instance != want.Instance || body.Code != want.Code ||
+		body.RequestID != want.RequestID {
+		t.Errorf("body = %+v, want %+v", body, want)
+	}
+}
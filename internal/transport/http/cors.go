@@ -0,0 +1,161 @@
+package http
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-Requested-With", "X-Request-ID", "Last-Event-ID"}
+)
+
+// CORS политика междоменных запросов из config.CORSConfig
+type CORS struct {
+	exact            []string
+	subdomains       []originPattern
+	any              bool
+	methods          []string
+	headers          []string
+	exposed          string
+	allowCredentials bool
+	maxAge           string
+}
+
+// originPattern источник с поддоменами: схема и суффикс хоста вида ".example.com"
+type originPattern struct {
+	scheme string
+	suffix string
+}
+
+func NewCORS(cfg config.CORSConfig) *CORS {
+	c := &CORS{
+		methods:          cfg.AllowedMethods,
+		headers:          cfg.AllowedHeaders,
+		exposed:          strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+	if len(c.methods) == 0 {
+		c.methods = defaultCORSMethods
+	}
+	if len(c.headers) == 0 {
+		c.headers = defaultCORSHeaders
+	}
+	if cfg.MaxAgeSeconds > 0 {
+		c.maxAge = strconv.Itoa(cfg.MaxAgeSeconds)
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "*":
+			c.any = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*")
+			c.subdomains = append(c.subdomains, originPattern{scheme: scheme, suffix: host})
+		default:
+			c.exact = append(c.exact, origin)
+		}
+	}
+	return c
+}
+
+// AllowedOrigin проверяет, разрешен ли источник запроса
+func (c *CORS) AllowedOrigin(origin string) bool {
+	return c.listed(origin) || c.any
+}
+
+// listed проверяет источник по явно перечисленным точным адресам и поддоменам
+func (c *CORS) listed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if slices.Contains(c.exact, origin) {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, p := range c.subdomains {
+		if u.Scheme == p.scheme && strings.HasSuffix(u.Host, p.suffix) && len(u.Host) > len(p.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware добавляет CORS заголовки для разрешенных источников, отвечая
+// разрешенному источнику его же адресом. Preflight запросы (OPTIONS с
+// Access-Control-Request-Method) завершаются здесь: 204 для разрешенных,
+// 403 для остальных. Прочие OPTIONS запросы передаются дальше.
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		w.Header().Add("Vary", "Origin")
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowed := c.AllowedOrigin(origin)
+
+		if preflight {
+			if !allowed || !c.allowedPreflight(r) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			c.setOrigin(w, origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.headers, ", "))
+			if c.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", c.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if allowed {
+			c.setOrigin(w, origin)
+			if c.exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedPreflight проверяет запрошенные метод и заголовки
+func (c *CORS) allowedPreflight(r *http.Request) bool {
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !slices.Contains(c.methods, method) {
+		return false
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(c.headers, func(h string) bool { return strings.EqualFold(h, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin отвечает источнику его адресом. Учетные данные разрешаются только
+// явно перечисленным источникам, но не совпавшим с "*".
+func (c *CORS) setOrigin(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.allowCredentials && c.listed(origin) {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,245 @@
+package http
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// serveCORS пропускает запрос через политику и сообщает, дошел ли он до обработчика
+func serveCORS(t *testing.T, cfg config.CORSConfig, r *http.Request) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	NewCORS(cfg).Middleware(next).ServeHTTP(w, r)
+	return w, reached
+}
+
+func corsRequest(method, origin string) *http.Request {
+	r := httptest.NewRequest(method, "/api/v1/comments", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	return r
+}
+
+func preflightRequest(origin, method, headers string) *http.Request {
+	r := corsRequest(http.MethodOptions, origin)
+	r.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		r.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return r
+}
+
+var testCORSConfig = config.CORSConfig{
+	AllowedOrigins:   []string{"http://localhost:3000", "https://*.example.com"},
+	ExposedHeaders:   []string{"X-Request-ID", "Retry-After"},
+	AllowCredentials: true,
+	MaxAgeSeconds:    600,
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "exact", origin: "http://localhost:3000", allowed: true},
+		{name: "exact case insensitive", origin: "HTTP://LOCALHOST:3000", allowed: true},
+		{name: "exact other port", origin: "http://localhost:3001"},
+		{name: "subdomain", origin: "https://app.example.com", allowed: true},
+		{name: "nested subdomain", origin: "https://a.b.example.com", allowed: true},
+		{name: "apex", origin: "https://example.com"},
+		{name: "subdomain other scheme", origin: "http://app.example.com"},
+		{name: "suffix lookalike", origin: "https://evilexample.com"},
+		{name: "disallowed", origin: "https://evil.test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reached := serveCORS(t, testCORSConfig, corsRequest(http.MethodGet, tt.origin))
+			if !reached {
+				t.Fatal("request did not reach handler")
+			}
+			acao := w.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed {
+				if acao != tt.origin {
+					t.Errorf("Access-Control-Allow-Origin = %q, want %q", acao, tt.origin)
+				}
+				if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+					t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+				}
+				if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID, Retry-After" {
+					t.Errorf("Access-Control-Expose-Headers = %q", got)
+				}
+				return
+			}
+			for _, h := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Expose-Headers"} {
+				if got := w.Header().Get(h); got != "" {
+					t.Errorf("%s = %q, want none", h, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		cfg := testCORSConfig
+		cfg.AllowCredentials = false
+		w, _ := serveCORS(t, cfg, corsRequest(http.MethodGet, "http://localhost:3000"))
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+			t.Errorf("Access-Control-Allow-Origin = %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+		}
+	})
+
+	t.Run("not sent for wildcard match", func(t *testing.T) {
+		cfg := config.CORSConfig{
+			AllowedOrigins:   []string{"http://localhost:3000", "*"},
+			AllowCredentials: true,
+		}
+		w, _ := serveCORS(t, cfg, corsRequest(http.MethodGet, "https://evil.test"))
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://evil.test" {
+			t.Errorf("Access-Control-Allow-Origin = %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+		}
+
+		w, _ = serveCORS(t, cfg, corsRequest(http.MethodGet, "http://localhost:3000"))
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("listed origin Access-Control-Allow-Credentials = %q, want true", got)
+		}
+	})
+
+	t.Run("config rejects wildcard", func(t *testing.T) {
+		cfg := config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() error = nil, want error")
+		}
+	})
+}
+
+func TestCORSPreflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       *http.Request
+		status  int
+		allowed bool
+	}{
+		{
+			name:    "allowed",
+			r:       preflightRequest("https://app.example.com", http.MethodPost, "content-type, authorization"),
+			status:  http.StatusNoContent,
+			allowed: true,
+		},
+		{
+			name:   "disallowed origin",
+			r:      preflightRequest("https://evil.test", http.MethodPost, ""),
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "disallowed method",
+			r:      preflightRequest("http://localhost:3000", "TRACE", ""),
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "disallowed header",
+			r:      preflightRequest("http://localhost:3000", http.MethodPost, "X-Secret"),
+			status: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reached := serveCORS(t, testCORSConfig, tt.r)
+			if reached {
+				t.Error("preflight reached handler")
+			}
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if !tt.allowed {
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+					t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+				}
+				return
+			}
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Content-Type, Authorization, X-Requested-With, X-Request-ID, Last-Event-ID",
+				"Access-Control-Max-Age":           "600",
+			}
+			for h, v := range want {
+				if got := w.Header().Get(h); got != v {
+					t.Errorf("%s = %q, want %q", h, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSOptionsWithoutRequestMethod(t *testing.T) {
+	w, reached := serveCORS(t, testCORSConfig, corsRequest(http.MethodOptions, "http://localhost:3000"))
+	if !reached {
+		t.Fatal("OPTIONS without Access-Control-Request-Method did not reach handler")
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want none", got)
+	}
+}
+
+func TestCORSVary(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *http.Request
+		want []string
+	}{
+		{
+			name: "no origin",
+			r:    corsRequest(http.MethodGet, ""),
+			want: []string{"Origin"},
+		},
+		{
+			name: "disallowed origin",
+			r:    corsRequest(http.MethodGet, "https://evil.test"),
+			want: []string{"Origin"},
+		},
+		{
+			name: "simple",
+			r:    corsRequest(http.MethodGet, "http://localhost:3000"),
+			want: []string{"Origin"},
+		},
+		{
+			name: "preflight",
+			r:    preflightRequest("http://localhost:3000", http.MethodPost, ""),
+			want: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name: "disallowed preflight",
+			r:    preflightRequest("https://evil.test", http.MethodPost, ""),
+			want: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := serveCORS(t, testCORSConfig, tt.r)
+			if got := w.Header().Values("Vary"); !slices.Equal(got, tt.want) {
+				t.Errorf("Vary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
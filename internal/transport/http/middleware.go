@@ -1,16 +1,30 @@
 package http
 
 import (
+	"bufio"
+	"commentservice/internal/clientip"
+	"commentservice/internal/requestid"
+	"commentservice/internal/tracing"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
-	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const requestIDKey contextKey = "request_id"
+const routeKey contextKey = "route"
+
+// unmatchedRoute метка запросов, не попавших ни в один маршрут. Путь
+// запроса в метки не попадает, чтобы число их значений оставалось ограниченным.
+const unmatchedRoute = "unmatched"
 
 type contextKey string
 
@@ -24,59 +38,76 @@ func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
-// RequestIDMiddleware добавляет уникальный ID к каждому запросу
+// Flush и Hijack нужны потоковым ответам (SSE, WebSocket), проходящим через обертку
+func (rw *responseWriter) Flush() {
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// RequestIDMiddleware добавляет ID к каждому запросу. ID, присланный
+// вызывающим сервисом в X-Request-ID, сохраняется, если он корректен; иначе
+// используется trace-id из traceparent, а при его отсутствии генерируется новый.
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := generateRequestID()
+		requestID := inboundRequestID(r)
 
-		w.Header().Set("X-Request-ID", requestID)
+		w.Header().Set(requestid.Header, requestID)
 
-		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		ctx := requestid.NewContext(r.Context(), requestID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func generateRequestID() string {
-	bytes := make([]byte, 8)
-	if _, err := rand.Read(bytes); err != nil {
-		return "fallback" + fmt.Sprintf("%d", time.Now().UnixNano())
+// ClientIPMiddleware добавляет в контекст IP адрес клиента для лимитов
+// запросов. X-Forwarded-For учитывается, только если trustForwardedFor;
+// адреса trustedProxies в нем пропускаются.
+func ClientIPMiddleware(trustForwardedFor bool, trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := clientip.NewContext(r.Context(), clientip.FromRequest(r, trustForwardedFor, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
-
-	return hex.EncodeToString(bytes)
 }
 
-// GetRequestID извлекает ID запроса из контекста
-func GetRequestID(ctx context.Context) string {
-	if id, ok := ctx.Value("request_id").(string); ok {
+// inboundRequestID выбирает ID запроса по входящим заголовкам
+func inboundRequestID(r *http.Request) string {
+	if id := r.Header.Get(requestid.Header); requestid.Valid(id) {
 		return id
 	}
+	if id, ok := requestid.FromTraceparent(r.Header.Get("traceparent")); ok {
+		return id
+	}
+	return requestid.New()
+}
 
-	return ""
+// GetRequestID извлекает ID запроса из контекста
+func GetRequestID(ctx context.Context) string {
+	return requestid.FromContext(ctx)
 }
 
-// CORSMiddleware добавляет CORS заголовки. По умолчанию разрешает все источники.
-func CORSMiddleware() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST ,PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-With, X-Request-ID")
-			w.Header().Set("Acess-Control-Max-Age", "86400")
-			w.Header().Set("Vary", "Origin")
-
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-			
-			next.ServeHTTP(w, r)
-		})
-	}
+// RequestObserver получает сведения о каждом обработанном запросе, например для метрик
+type RequestObserver interface {
+	ObserveHTTPRequest(route, method string, status int, duration time.Duration)
 }
 
-// LoggingMiddleware логирует информацию о каждом запросе.
-func LoggingMiddleware(log *slog.Logger) func(http.Handler) http.Handler {
+// LoggingMiddleware логирует информацию о каждом запросе и передает ее observers.
+// ID запроса попадает в запись из контекста, поэтому RequestIDMiddleware
+// подключается снаружи.
+func LoggingMiddleware(log *slog.Logger, observers ...RequestObserver) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -85,18 +116,23 @@ func LoggingMiddleware(log *slog.Logger) func(http.Handler) http.Handler {
 				ResponseWriter: w,
 				statusCode:     http.StatusOK,
 			}
+			route := &routeInfo{template: unmatchedRoute}
+			r = r.WithContext(context.WithValue(r.Context(), routeKey, route))
 
 			next.ServeHTTP(rw, r)
 
 			duration := time.Since(start)
+			for _, observer := range observers {
+				observer.ObserveHTTPRequest(route.template, r.Method, rw.statusCode, duration)
+			}
 
-			requestID := GetRequestID(r.Context())
-
-			log.Info(
+			log.InfoContext(
+				r.Context(),
 				"HTTP request",
-				"request_id", requestID,
 				"method", r.Method,
 				"path", r.URL.Path,
+				"route", route.template,
+				"status", rw.statusCode,
 				"duration", duration,
 				"user_agent", r.UserAgent(),
 				"remote_addr", r.RemoteAddr,
@@ -104,3 +140,52 @@ func LoggingMiddleware(log *slog.Logger) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// routeInfo шаблон маршрута, заполняемый RouteMiddleware внутри роутера
+type routeInfo struct {
+	template string
+}
+
+// RouteMiddleware сообщает LoggingMiddleware шаблон маршрута, совпавшего с
+// запросом. Подключается к mux.Router через Use.
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(routeKey).(*routeInfo); ok {
+			if template, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil {
+				info.template = template
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TracingMiddleware продолжает трассу из заголовков traceparent входящего
+// запроса или начинает новую. Имя спана - шаблон маршрута, поэтому
+// middleware подключается внутри LoggingMiddleware.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			))
+		defer span.End()
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		if info, ok := r.Context().Value(routeKey).(*routeInfo); ok {
+			span.SetName(r.Method + " " + info.template)
+			span.SetAttributes(semconv.HTTPRoute(info.template))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.statusCode))
+		if rw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+		}
+	})
+}
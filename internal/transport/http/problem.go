@@ -0,0 +1,58 @@
+package http
+
+import (
+	"commentservice/internal/apperr"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// problem тело ответа с ошибкой в формате RFC 7807 (application/problem+json).
+// Code - код ошибки из apperr, RequestID - ID запроса, по которому ошибку
+// можно найти в логах всех сервисов.
+type problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	RequestID     string         `json:"request_id,omitempty"`
+	Errors        []string       `json:"errors,omitempty"`
+	InvalidParams []invalidParam `json:"invalid_params,omitempty"`
+}
+
+// invalidParam поле запроса, не прошедшее проверку
+type invalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// WriteProblem отправляет ошибку в формате application/problem+json. Тексты
+// errs попадают в ответ только для ошибок клиента, чтобы не раскрывать
+// внутренние детали. Общий для api и middleware, чтобы тела ошибок не расходились.
+func WriteProblem(w http.ResponseWriter, r *http.Request, detail string, status int, code string, errs ...error) {
+	response := problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: GetRequestID(r.Context()),
+	}
+	if status < http.StatusInternalServerError {
+		for _, err := range errs {
+			response.Errors = append(response.Errors, err.Error())
+			var invalid *apperr.ValidationError
+			if errors.As(err, &invalid) {
+				response.InvalidParams = append(response.InvalidParams,
+					invalidParam{Name: invalid.Field, Reason: invalid.Reason})
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consumer читает сообщения топика в составе группы потребителей. Смещение
+// фиксируется явно через CommitMessages, после того как сообщение обработано.
+type Consumer interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// ConsumerFactory создает новый Consumer. Worker создает потребителя на
+// каждый запуск, чтобы после перезапуска продолжить с зафиксированного смещения.
+type ConsumerFactory func() Consumer
+
+// NewConsumerFactory возвращает фабрику потребителей топика в группе groupID
+func NewConsumerFactory(brokers []string, topic, groupID string) ConsumerFactory {
+	return func() Consumer {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			GroupID: groupID,
+			Topic:   topic,
+		})
+	}
+}
+
+// fanoutCommitInterval период фиксации смещений потребителя событий
+const fanoutCommitInterval = time.Second
+
+// NewFanoutConsumerFactory возвращает фабрику потребителей топика событий.
+// Новая группа начинает чтение с конца топика: реплике нужны только новые
+// события. Смещения фиксируются периодически, в фоне.
+func NewFanoutConsumerFactory(brokers []string, topic, groupID string) ConsumerFactory {
+	return func() Consumer {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        brokers,
+			GroupID:        groupID,
+			Topic:          topic,
+			StartOffset:    kafka.LastOffset,
+			CommitInterval: fanoutCommitInterval,
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"commentservice/internal/models"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// EventSink принимает события комментариев с ID, присвоенным по смещению в Kafka
+type EventSink interface {
+	PublishWithID(event models.CommentEvent, id uint64)
+}
+
+// Fanout читает события комментариев из топика и передает их в EventSink.
+// Каждая реплика читает топик в собственной группе и получает все события,
+// в том числе вызванные запросами к другим репликам. События одной новости
+// публикуются с ключом news_id и попадают в одну партицию, поэтому смещение
+// годится как возрастающий ID события новости.
+type Fanout struct {
+	newConsumer ConsumerFactory
+	sink        EventSink
+	log         *slog.Logger
+}
+
+func NewFanout(newConsumer ConsumerFactory, sink EventSink, log *slog.Logger) *Fanout {
+	return &Fanout{
+		newConsumer: newConsumer,
+		sink:        sink,
+		log:         log.With("worker", "stream_fanout"),
+	}
+}
+
+// Run передает события до отмены ctx. Некорректные сообщения пропускаются.
+func (f *Fanout) Run(ctx context.Context) error {
+	consumer := f.newConsumer()
+	defer func() {
+		if err := consumer.Close(); err != nil {
+			f.log.ErrorContext(ctx, "failed to close Kafka consumer", "error", err)
+		}
+	}()
+
+	f.log.InfoContext(ctx, "stream fanout started")
+	for {
+		msg, err := consumer.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				f.log.InfoContext(ctx, "stream fanout stopped")
+				return nil
+			}
+			f.log.ErrorContext(ctx, "failed to read message from Kafka", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(consumeRetryDelay):
+			}
+			continue
+		}
+
+		var event models.CommentEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			f.log.WarnContext(ctx, "failed to decode comment event",
+				"kafka_partition", msg.Partition, "kafka_offset", msg.Offset, "error", err)
+		} else {
+			f.sink.PublishWithID(event, uint64(msg.Offset)+1)
+		}
+
+		if err := consumer.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			f.log.WarnContext(ctx, "failed to commit message", "error", err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/models"
+	"commentservice/internal/requestid"
+	"commentservice/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+)
+
+const (
+	statusSuccess     = "success"
+	statusError       = "error"
+	statusRateLimited = "rate_limited"
+)
+
+// Handlers обрабатывает запросы к CommentService, пришедшие через Kafka
+type Handlers struct {
+	commentService service.CommentService
+	log            *slog.Logger
+}
+
+func NewHandlers(commentService service.CommentService, log *slog.Logger) *Handlers {
+	return &Handlers{
+		commentService: commentService,
+		log:            log,
+	}
+}
+
+// ListComments обрабатывает models.ListCommentRequest и возвращает
+// закодированный models.ListCommentResponse с тем же RequestID
+func (h *Handlers) ListComments(ctx context.Context, value []byte) []byte {
+	var req models.ListCommentRequest
+	if err := json.Unmarshal(value, &req); err != nil {
+		ctx, requestID := correlate(ctx, "")
+		h.log.ErrorContext(ctx, "failed to decode list comments request", "error", err)
+		return encode(models.ListCommentResponse{
+			RequestID: requestID,
+			Status:    statusError,
+			Error:     apperr.CodeValidation,
+			Detail:    fmt.Sprintf("failed to decode request: %v", err),
+		})
+	}
+
+	ctx, req.RequestID = correlate(ctx, req.RequestID)
+	resp := models.ListCommentResponse{RequestID: req.RequestID}
+
+	newsID, err := strconv.Atoi(req.NewsID)
+	if err != nil {
+		resp.Status = statusError
+		resp.Error, resp.Detail = errorFields(apperr.Invalid("news_id", "not a number: %q", req.NewsID))
+		return encode(resp)
+	}
+
+	page, err := h.commentService.GetComments(ctx, models.CommentQuery{
+		NewsID:        newsID,
+		Limit:         req.Limit,
+		Offset:        req.Offset,
+		Cursor:        req.Cursor,
+		Sort:          req.Sort,
+		WithReactions: req.Reactions,
+	})
+	if err != nil {
+		h.log.ErrorContext(ctx, "failed to get comments", "news_id", newsID, "error", err)
+		resp.Status = statusError
+		resp.Error, resp.Detail = errorFields(err)
+		return encode(resp)
+	}
+
+	resp.Status = statusSuccess
+	resp.Data = page.Comments
+	resp.Total = page.Total
+	resp.NextCursor = page.NextCursor
+	resp.PrevCursor = page.PrevCursor
+	return encode(resp)
+}
+
+// AddComment обрабатывает models.AddCommentRequest и возвращает
+// закодированный models.AddCommentResponse с тем же RequestID
+func (h *Handlers) AddComment(ctx context.Context, value []byte) []byte {
+	var req models.AddCommentRequest
+	if err := json.Unmarshal(value, &req); err != nil {
+		ctx, requestID := correlate(ctx, "")
+		h.log.ErrorContext(ctx, "failed to decode add comment request", "error", err)
+		return encode(models.AddCommentResponse{
+			RequestID: requestID,
+			Status:    statusError,
+			Error:     apperr.CodeValidation,
+			Detail:    fmt.Sprintf("failed to decode request: %v", err),
+		})
+	}
+
+	ctx, req.RequestID = correlate(ctx, req.RequestID)
+	resp := models.AddCommentResponse{RequestID: req.RequestID}
+
+	saved, err := h.commentService.AddComment(ctx, req.Data)
+	if err != nil {
+		resp.Status = statusError
+		resp.Error, resp.Detail = errorFields(err)
+		var rateLimit *apperr.RateLimitError
+		if errors.As(err, &rateLimit) {
+			h.log.WarnContext(ctx, "add comment rate limited", "news_id", req.Data.NewsID)
+			resp.Status = statusRateLimited
+			resp.RetryAfter = int(math.Ceil(rateLimit.RetryAfter.Seconds()))
+			return encode(resp)
+		}
+		h.log.ErrorContext(ctx, "failed to add comment", "news_id", req.Data.NewsID, "error", err)
+		return encode(resp)
+	}
+
+	resp.Status = statusSuccess
+	resp.Data = &saved
+	return encode(resp)
+}
+
+// errorFields возвращает код и описание ошибки для полей Error и Detail ответа
+func errorFields(err error) (string, string) {
+	return apperr.Code(err), err.Error()
+}
+
+// correlate выбирает ID запроса: из тела запроса, иначе из заголовка
+// сообщения (уже в ctx), иначе новый. Возвращает контекст с этим ID, чтобы
+// он попал в логи и в заголовок ответа.
+func correlate(ctx context.Context, bodyID string) (context.Context, string) {
+	if bodyID != "" {
+		return requestid.NewContext(ctx, bodyID), bodyID
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := requestid.New()
+	return requestid.NewContext(ctx, id), id
+}
+
+// encode кодирует ответ. Ответные структуры всегда сериализуемы, поэтому
+// ошибка кодирования не ожидается.
+func encode(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
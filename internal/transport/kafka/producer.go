@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"commentservice/internal/requestid"
+	"commentservice/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// producerBatchTimeout сокращает ожидание накопления пачки при синхронной записи
+const producerBatchTimeout = 10 * time.Millisecond
+
+// Producer совместим с kafkawrapper.Prod и дополнительно умеет публиковать
+// сообщения с ключом: сообщения с одинаковым ключом попадают в одну партицию
+// и сохраняют порядок.
+type Producer struct {
+	writer   *kafka.Writer
+	brokers  []string
+	observer Observer
+}
+
+// Observer получает сведения об обработанных и опубликованных сообщениях, например для метрик
+type Observer interface {
+	ObserveConsume(worker string, lag int64, err error)
+	ObserveProduce(topic string, duration time.Duration, err error)
+}
+
+// noopObserver используется, если Observer не задан
+type noopObserver struct{}
+
+func (noopObserver) ObserveConsume(string, int64, error)         {}
+func (noopObserver) ObserveProduce(string, time.Duration, error) {}
+
+func NewProducer(brokers []string) *Producer {
+	return &Producer{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: producerBatchTimeout,
+		},
+		brokers:  brokers,
+		observer: noopObserver{},
+	}
+}
+
+// SetObserver задает получателя сведений о публикации. Вызывается до начала работы.
+func (p *Producer) SetObserver(observer Observer) {
+	p.observer = observer
+}
+
+// SendMessage публикует сообщение без ключа
+func (p *Producer) SendMessage(ctx context.Context, topic string, message []byte) error {
+	return p.SendKeyedMessage(ctx, topic, nil, message)
+}
+
+// SendKeyedMessage публикует сообщение с ключом партиционирования
+func (p *Producer) SendKeyedMessage(ctx context.Context, topic string, key, message []byte) error {
+	msg := kafka.Message{
+		Topic: topic,
+		Key:   key,
+		Value: message,
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		headerCarrier{&msg.Headers}.Set(requestid.Header, id)
+	}
+	ctx, span := startProduceSpan(ctx, &msg)
+	defer span.End()
+
+	start := time.Now()
+	err := p.writer.WriteMessages(ctx, msg)
+	p.observer.ObserveProduce(topic, time.Since(start), err)
+	tracing.RecordError(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to write message to topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Ping проверяет, что хотя бы один брокер доступен
+func (p *Producer) Ping(ctx context.Context) error {
+	return PingTopic(ctx, p.brokers, "")
+}
+
+func (p *Producer) Close() error {
+	return p.writer.Close()
+}
+
+// PingTopic подключается к первому доступному брокеру и, если topic не пуст,
+// проверяет, что топик существует
+func PingTopic(ctx context.Context, brokers []string, topic string) error {
+	var errs []error
+	for _, broker := range brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		if topic == "" {
+			_, err = conn.Brokers()
+		} else {
+			_, err = conn.ReadPartitions(topic)
+		}
+		if err != nil {
+			return fmt.Errorf("broker %s: %w", broker, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no Kafka broker available: %w", errors.Join(errs...))
+}
@@ -0,0 +1,194 @@
+// Package kafka содержит обработчики запросов к сервису комментариев,
+// приходящих через Kafka по схеме запрос/ответ.
+package kafka
+
+import (
+	"commentservice/internal/auth"
+	"commentservice/internal/logging"
+	"commentservice/internal/requestid"
+	"commentservice/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	kfk "github.com/Fau1con/kafkawrapper"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// consumeRetryDelay пауза после ошибки чтения, чтобы не крутить пустой цикл
+	consumeRetryDelay = time.Second
+	// processTimeout ограничивает обработку одного сообщения, в том числе
+	// при остановке, когда контекст воркера уже отменен
+	processTimeout = 30 * time.Second
+)
+
+// Handler обрабатывает значение сообщения и возвращает ответ для публикации
+type Handler func(ctx context.Context, value []byte) []byte
+
+// Worker читает запросы из одного топика и публикует ответы в другой.
+// Смещение фиксируется только после публикации ответа, поэтому при ошибке
+// публикации Run завершается, а сообщение будет прочитано повторно после
+// перезапуска воркера.
+type Worker struct {
+	name        string
+	newConsumer ConsumerFactory
+	producer    kfk.Prod
+	replyTopic  string
+	handle      Handler
+	log         *slog.Logger
+	observer    Observer
+	auth        Authenticator
+
+	running atomic.Bool
+}
+
+// Authenticator проверяет API ключ сервиса, приславшего запрос
+type Authenticator interface {
+	Authenticate(key string) (auth.Principal, error)
+}
+
+func NewWorker(
+	name string,
+	newConsumer ConsumerFactory,
+	producer kfk.Prod,
+	replyTopic string,
+	handle Handler,
+	log *slog.Logger,
+) *Worker {
+	return &Worker{
+		name:        name,
+		newConsumer: newConsumer,
+		producer:    producer,
+		replyTopic:  replyTopic,
+		handle:      handle,
+		log:         log.With("worker", name),
+		observer:    noopObserver{},
+	}
+}
+
+// SetObserver задает получателя сведений об обработке. Вызывается до Run.
+func (w *Worker) SetObserver(observer Observer) {
+	w.observer = observer
+}
+
+// SetAuthenticator включает проверку API ключа из заголовка X-API-Key.
+// Сервис с верным ключом становится субъектом запроса; запрос без ключа или
+// с неверным ключом обрабатывается анонимно, и запись в нем будет отклонена
+// сервисом. Вызывается до Run.
+func (w *Worker) SetAuthenticator(a Authenticator) {
+	w.auth = a
+}
+
+// ErrWorkerStopped воркер не запущен или перезапускается после сбоя
+var ErrWorkerStopped = errors.New("kafka worker is not running")
+
+// Ping сообщает, работает ли воркер в данный момент
+func (w *Worker) Ping(ctx context.Context) error {
+	if !w.running.Load() {
+		return fmt.Errorf("%s: %w", w.name, ErrWorkerStopped)
+	}
+	return nil
+}
+
+// Name возвращает имя воркера
+func (w *Worker) Name() string {
+	return w.name
+}
+
+// Run обрабатывает сообщения до отмены ctx. Сообщение, чтение которого уже
+// началось, дообрабатывается и фиксируется даже после отмены ctx.
+func (w *Worker) Run(ctx context.Context) error {
+	w.running.Store(true)
+	defer w.running.Store(false)
+
+	consumer := w.newConsumer()
+	defer func() {
+		if err := consumer.Close(); err != nil {
+			w.log.ErrorContext(ctx, "failed to close Kafka consumer", "error", err)
+		}
+	}()
+
+	w.log.InfoContext(ctx, "kafka worker started", "reply_topic", w.replyTopic)
+	for {
+		msg, err := consumer.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				w.log.InfoContext(ctx, "kafka worker stopped")
+				return nil
+			}
+			w.log.ErrorContext(ctx, "failed to read message from Kafka", "error", err)
+			w.observer.ObserveConsume(w.name, -1, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(consumeRetryDelay):
+			}
+			continue
+		}
+
+		processCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), processTimeout)
+		err = w.process(processCtx, consumer, msg)
+		cancel()
+		w.observer.ObserveConsume(w.name, consumerLag(msg), err)
+		if err != nil {
+			return fmt.Errorf("worker %s, offset %d: %w", w.name, msg.Offset, err)
+		}
+	}
+}
+
+// process обрабатывает сообщение, публикует ответ и фиксирует смещение.
+// Трасса продолжается из заголовков запроса и передается в заголовки ответа.
+func (w *Worker) process(ctx context.Context, consumer Consumer, msg kafka.Message) (err error) {
+	ctx, span := startConsumeSpan(ctx, w.name, &msg)
+	if id := (headerCarrier{&msg.Headers}).Get(requestid.Header); requestid.Valid(id) {
+		ctx = requestid.NewContext(ctx, id)
+	}
+	ctx = logging.WithAttrs(ctx,
+		slog.String("kafka_topic", msg.Topic),
+		slog.Int("kafka_partition", msg.Partition),
+		slog.Int64("kafka_offset", msg.Offset),
+	)
+	if w.auth != nil {
+		ctx = w.authenticate(ctx, msg)
+	}
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	reply := w.handle(ctx, msg.Value)
+
+	if err := w.producer.SendMessage(ctx, w.replyTopic, reply); err != nil {
+		return fmt.Errorf("failed to write reply to Kafka: %w", err)
+	}
+	if err := consumer.CommitMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to commit Kafka offset: %w", err)
+	}
+	return nil
+}
+
+// authenticate добавляет в контекст сервис, которому принадлежит API ключ сообщения
+func (w *Worker) authenticate(ctx context.Context, msg kafka.Message) context.Context {
+	key := (headerCarrier{&msg.Headers}).Get(auth.APIKeyHeader)
+	if key == "" {
+		return ctx
+	}
+	principal, err := w.auth.Authenticate(key)
+	if err != nil {
+		w.log.WarnContext(ctx, "invalid API key in Kafka message", "error", err)
+		return ctx
+	}
+	return logging.WithAttrs(auth.NewContext(ctx, principal), slog.String("service", principal.ID))
+}
+
+// consumerLag число сообщений партиции после msg; -1, если брокер не сообщил
+// верхнюю границу
+func consumerLag(msg kafka.Message) int64 {
+	if msg.HighWaterMark <= 0 {
+		return -1
+	}
+	return max(msg.HighWaterMark-msg.Offset-1, 0)
+}
@@ -1,16 +1,72 @@
 package storage
 
 import (
+	"commentservice/internal/apperr"
 	"commentservice/internal/models"
 	"context"
+	"time"
+	"unicode/utf8"
 )
 
+// MaxContentLength наибольшая длина текста комментария в символах; совпадает
+// с размером столбца comments.content
+const MaxContentLength = 2000
+
+// ErrCommentNotFound возвращается, если комментарий с указанным ID отсутствует
+var ErrCommentNotFound = apperr.New(apperr.ErrNotFound, "comment not found")
+
+// ErrCommentDeleted возвращается при попытке изменить удаленный комментарий
+var ErrCommentDeleted = apperr.New(apperr.ErrConflict, "comment is deleted")
+
+// ValidateContent проверяет, что текст помещается в MaxContentLength символов.
+// Вызывается и сервисом, и хранилищами, чтобы длинный текст отклонялся
+// одинаково во всех реализациях, а не ошибкой базы.
+func ValidateContent(content string) error {
+	if n := utf8.RuneCountInString(content); n > MaxContentLength {
+		return apperr.Invalid("content", "must be at most %d characters, got %d", MaxContentLength, n)
+	}
+	return nil
+}
+
 type CommentsStorage interface {
-	AddComment(ctx context.Context, newsID int, comment string) error
-	GetComments(ctx context.Context, newsID int) ([]models.Comment, error)
+	AddComment(ctx context.Context, comment models.Comment) (models.Comment, error)
+	GetComment(ctx context.Context, commentID int) (models.Comment, error)
+	// GetCommentsByIDs возвращает найденные комментарии по ID; отсутствующих ID в результате нет
+	GetCommentsByIDs(ctx context.Context, commentIDs []int) (map[int]models.Comment, error)
+	GetComments(ctx context.Context, query models.CommentQuery) (models.CommentPage, error)
+	// GetCommentThread возвращает узлы дерева для страницы корневых комментариев
+	// и общее число корневых комментариев новости
+	GetCommentThread(ctx context.Context, query models.ThreadQuery) ([]models.CommentNode, int, error)
+	// UpdateComment сохраняет правку и сообщает, был ли комментарий отмечен цензурой до нее
+	UpdateComment(ctx context.Context, comment models.Comment) (updated models.Comment, wasCens bool, err error)
+	// DeleteComment мягко удаляет комментарий и сообщает, изменилась ли строка;
+	// повторное удаление возвращает false без ошибки
+	DeleteComment(ctx context.Context, commentID int) (deleted bool, err error)
+	// GetCommentRevisions возвращает историю правок; у удаленного комментария она пуста
+	GetCommentRevisions(ctx context.Context, commentID int) ([]models.CommentRevision, error)
+	// GetCommentRevisionsByIDs возвращает истории правок по ID комментариев; у
+	// комментария без правок или удаленного пустой срез, отсутствующих
+	// комментариев в результате нет
+	GetCommentRevisionsByIDs(ctx context.Context, commentIDs []int) (map[int][]models.CommentRevision, error)
+	// SetReaction ставит или меняет реакцию пользователя и возвращает новую сводку
+	SetReaction(ctx context.Context, commentID int, userID, reaction string) (models.ReactionSummary, error)
+	// RemoveReaction снимает реакцию пользователя; снятие отсутствующей реакции не ошибка
+	RemoveReaction(ctx context.Context, commentID int, userID string) (models.ReactionSummary, error)
+	// GetReactionSummaries возвращает сводки реакций по ID комментариев; Mine
+	// заполняется для userID. Отсутствующих комментариев в результате нет.
+	GetReactionSummaries(ctx context.Context, commentIDs []int, userID string) (map[int]models.ReactionSummary, error)
 	Close()
 }
 type NewsStorage interface {
 	NewsExists(ctx context.Context, newsID int) (bool, error)
+	// NewsExistsBatch проверяет несколько новостей за раз; в результате есть ключ для каждого ID
+	NewsExistsBatch(ctx context.Context, newsIDs []int) (map[int]bool, error)
 	Close()
 }
+
+// OutboxStorage очередь событий комментариев, записанных вместе с изменениями
+type OutboxStorage interface {
+	RelayOutbox(ctx context.Context, limit int, publish PublishFunc) (int, error)
+	// PruneOutbox удаляет события, опубликованные раньше before, и возвращает их число
+	PruneOutbox(ctx context.Context, before time.Time) (int, error)
+}
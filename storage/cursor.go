@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// ErrInvalidCursor возвращается, если курсор пагинации не удалось разобрать
+var ErrInvalidCursor = apperr.New(apperr.ErrValidation, "invalid cursor")
+
+// cursor позиция в списке комментариев порядка Sort по ключу (created_at, id)
+// или, для порядков по оценке, (Score, id). Backward означает выборку
+// страницы, предшествующей позиции. У курсоров порядка по умолчанию Sort пуст.
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+	Sort      string    `json:"s,omitempty"`
+	Score     float64   `json:"k,omitempty"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// encodeCursor кодирует курсор в непрозрачную для клиента строку
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor разбирает строку, полученную от encodeCursor для порядка sort
+func decodeCursor(s string, sort string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID < 1 || normalizeSort(c.Sort) != sort {
+		return cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// buildPage формирует страницу порядка sort из не более чем limit+1
+// выбранных строк и их оценок. При обратной выборке строки приходят в
+// обратном порядке и разворачиваются.
+func buildPage(rows []models.Comment, scores []float64, sort string, limit int, after *cursor, offset int) models.CommentPage {
+	backward := after != nil && after.Backward
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows, scores = rows[:limit], scores[:limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+		slices.Reverse(scores)
+	}
+	if rows == nil {
+		rows = []models.Comment{}
+	}
+
+	page := models.CommentPage{Comments: rows}
+	if len(rows) == 0 {
+		return page
+	}
+
+	var hasNext, hasPrev bool
+	switch {
+	case after == nil:
+		hasNext, hasPrev = hasMore, offset > 0
+	case backward:
+		hasNext, hasPrev = true, hasMore
+	default:
+		hasNext, hasPrev = hasMore, true
+	}
+
+	if sort == models.SortOldest {
+		sort = ""
+	}
+	first, last := 0, len(rows)-1
+	if hasNext {
+		page.NextCursor = encodeCursor(cursor{
+			CreatedAt: rows[last].CreatedAt, ID: rows[last].CommentID, Sort: sort, Score: scores[last],
+		})
+	}
+	if hasPrev {
+		page.PrevCursor = encodeCursor(cursor{
+			CreatedAt: rows[first].CreatedAt, ID: rows[first].CommentID, Sort: sort, Score: scores[first], Backward: true,
+		})
+	}
+	return page
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewStorageWithPool создает Storage над готовым пулом для тестов
+func NewStorageWithPool(db *pgxpool.Pool, log *slog.Logger) *Storage {
+	return &Storage{db: db, name: "comments", log: log}
+}
+
+// SetNow подменяет часы кэша NewsAPIStorage
+func (s *NewsAPIStorage) SetNow(now func() time.Time) {
+	s.now = now
+}
+
+// CacheLen возвращает число записей в кэше NewsAPIStorage
+func (s *NewsAPIStorage) CacheLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.cache)
+}
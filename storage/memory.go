@@ -0,0 +1,561 @@
+package storage
+
+import (
+	"cmp"
+	"commentservice/internal/apperr"
+	"commentservice/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// memoryComment запись комментария в памяти вместе со временем удаления,
+// реакциями пользователей и их счетчиками
+type memoryComment struct {
+	comment   models.Comment
+	deletedAt *time.Time
+	reactions map[string]string
+	upvotes   int
+	downvotes int
+}
+
+// summary возвращает сводку реакций для пользователя userID
+func (m *memoryComment) summary(userID string) models.ReactionSummary {
+	return newSummary(m.upvotes, m.downvotes, m.reactions[userID])
+}
+
+// view возвращает комментарий в том виде, в котором его отдает Postgres-хранилище
+func (m *memoryComment) view() models.Comment {
+	comment := m.comment
+	comment.Deleted = m.deletedAt != nil
+	if comment.Deleted {
+		comment.Content = ""
+	}
+	return comment
+}
+
+// MemoryStorage потокобезопасная реализация CommentsStorage и OutboxStorage
+// в памяти для тестов и локальной разработки. Поведение совпадает с Storage.
+type MemoryStorage struct {
+	mu        sync.RWMutex
+	comments  map[int]*memoryComment
+	revisions map[int][]models.CommentRevision
+	outbox    []models.OutboxEvent
+	published map[int64]time.Time
+
+	nextCommentID  int
+	nextRevisionID int
+	nextEventID    int64
+
+	// relayMu не дает двум relay обрабатывать outbox одновременно
+	relayMu sync.Mutex
+	log     *slog.Logger
+}
+
+func NewMemoryStorage(log *slog.Logger) *MemoryStorage {
+	return &MemoryStorage{
+		comments:  make(map[int]*memoryComment),
+		revisions: make(map[int][]models.CommentRevision),
+		published: make(map[int64]time.Time),
+		log:       log,
+	}
+}
+
+// now возвращает текущее время с точностью Postgres TIMESTAMP
+func (s *MemoryStorage) now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// AddComment добавляет комментарий и возвращает его с присвоенным ID
+func (s *MemoryStorage) AddComment(ctx context.Context, comment models.Comment) (models.Comment, error) {
+	if err := ValidateContent(comment.Content); err != nil {
+		return models.Comment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if comment.ParentID != nil {
+		if _, ok := s.comments[*comment.ParentID]; !ok {
+			return models.Comment{}, fmt.Errorf("failed to save comment: parent comment %d does not exist", *comment.ParentID)
+		}
+	}
+
+	s.nextCommentID++
+	now := s.now()
+	comment.CommentID = s.nextCommentID
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	comment.Deleted = false
+	if comment.ParentID != nil {
+		parentID := *comment.ParentID
+		comment.ParentID = &parentID
+	}
+	s.comments[comment.CommentID] = &memoryComment{comment: comment, reactions: make(map[string]string)}
+
+	s.appendOutbox(commentEvents(ctx, models.EventCommentCreated, comment, comment.Cens))
+
+	s.log.InfoContext(ctx, "comment added successfully", "newsID", comment.NewsID, "commentID", comment.CommentID)
+	return comment, nil
+}
+
+// GetComment получает комментарий по его ID
+func (s *MemoryStorage) GetComment(ctx context.Context, commentID int) (models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.comments[commentID]
+	if !ok {
+		return models.Comment{}, ErrCommentNotFound
+	}
+	return record.view(), nil
+}
+
+// GetCommentsByIDs получает несколько комментариев по ID
+func (s *MemoryStorage) GetCommentsByIDs(ctx context.Context, commentIDs []int) (map[int]models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[int]models.Comment, len(commentIDs))
+	for _, id := range commentIDs {
+		if record, ok := s.comments[id]; ok {
+			result[id] = record.view()
+		}
+	}
+	return result, nil
+}
+
+// GetComments получает страницу комментариев по ID новости
+func (s *MemoryStorage) GetComments(ctx context.Context, query models.CommentQuery) (models.CommentPage, error) {
+	if query.NewsID < 1 {
+		return models.CommentPage{}, apperr.Invalid("news_id", "must be positive, got %d", query.NewsID)
+	}
+	if query.Limit < 1 {
+		return models.CommentPage{}, apperr.Invalid("limit", "must be positive, got %d", query.Limit)
+	}
+	if query.Offset < 0 {
+		return models.CommentPage{}, apperr.Invalid("offset", "must not be negative, got %d", query.Offset)
+	}
+
+	sort := normalizeSort(query.Sort)
+	spec, ok := sortSpecs[sort]
+	if !ok {
+		return models.CommentPage{}, apperr.Invalid("sort", "unknown sort order %q", query.Sort)
+	}
+
+	var after *cursor
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor, sort)
+		if err != nil {
+			return models.CommentPage{}, err
+		}
+		after = &c
+	}
+
+	s.mu.RLock()
+	all := s.newsComments(query.NewsID, sort, spec)
+	s.mu.RUnlock()
+
+	var selected []scoredComment
+	switch {
+	case after == nil:
+		selected = all[min(query.Offset, len(all)):]
+	case after.Backward:
+		for i := len(all) - 1; i >= 0; i-- {
+			if all[i].compare(spec, after) < 0 {
+				selected = append(selected, all[i])
+			}
+		}
+	default:
+		for _, comment := range all {
+			if comment.compare(spec, after) > 0 {
+				selected = append(selected, comment)
+			}
+		}
+	}
+	selected = selected[:min(query.Limit+1, len(selected))]
+
+	rows := make([]models.Comment, 0, len(selected))
+	scores := make([]float64, 0, len(selected))
+	for _, comment := range selected {
+		rows = append(rows, comment.comment)
+		scores = append(scores, comment.score)
+	}
+
+	page := buildPage(rows, scores, sort, query.Limit, after, query.Offset)
+	page.Total = len(all)
+	return page, nil
+}
+
+// GetCommentThread получает комментарии новости вместе с ответами не глубже
+// query.MaxDepth уровней для страницы корневых комментариев
+func (s *MemoryStorage) GetCommentThread(ctx context.Context, query models.ThreadQuery) ([]models.CommentNode, int, error) {
+	newsID := query.NewsID
+	if newsID < 1 {
+		return nil, 0, apperr.Invalid("news_id", "must be positive, got %d", newsID)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	children := make(map[int][]models.Comment)
+	var level []models.Comment
+	for _, record := range s.comments {
+		comment := record.view()
+		if comment.ParentID != nil {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+		} else if comment.NewsID == newsID {
+			level = append(level, comment)
+		}
+	}
+
+	total := len(level)
+	slices.SortFunc(level, compareComments)
+	level = level[min(max(query.Offset, 0), total):]
+	if query.Limit > 0 && query.Limit < len(level) {
+		level = level[:query.Limit]
+	}
+
+	var nodes []models.CommentNode
+	for depth := 0; depth <= query.MaxDepth && len(level) > 0; depth++ {
+		slices.SortFunc(level, compareComments)
+
+		var next []models.Comment
+		for _, comment := range level {
+			nodes = append(nodes, models.CommentNode{
+				Comment:    comment,
+				Depth:      depth,
+				ReplyCount: len(children[comment.CommentID]),
+			})
+			next = append(next, children[comment.CommentID]...)
+		}
+		level = next
+	}
+
+	return nodes, total, nil
+}
+
+// UpdateComment заменяет текст и признаки цензуры комментария, сохраняя предыдущий текст в истории
+func (s *MemoryStorage) UpdateComment(ctx context.Context, comment models.Comment) (models.Comment, bool, error) {
+	if err := ValidateContent(comment.Content); err != nil {
+		return models.Comment{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.comments[comment.CommentID]
+	if !ok {
+		return models.Comment{}, false, ErrCommentNotFound
+	}
+	if record.deletedAt != nil {
+		return models.Comment{}, false, ErrCommentDeleted
+	}
+
+	now := s.now()
+	s.nextRevisionID++
+	s.revisions[comment.CommentID] = append(s.revisions[comment.CommentID], models.CommentRevision{
+		RevisionID: s.nextRevisionID,
+		CommentID:  comment.CommentID,
+		Content:    record.comment.Content,
+		EditedAt:   now,
+	})
+
+	wasCens := record.comment.Cens
+	record.comment.Content = comment.Content
+	record.comment.Cens = comment.Cens
+	record.comment.CensReason = comment.CensReason
+	record.comment.UpdatedAt = now
+
+	updated := record.view()
+	s.appendOutbox(commentEvents(ctx, models.EventCommentUpdated, updated, updated.Cens && !wasCens))
+
+	s.log.InfoContext(ctx, "comment updated successfully", "newsID", updated.NewsID, "commentID", updated.CommentID)
+	return updated, wasCens, nil
+}
+
+// DeleteComment мягко удаляет комментарий. Повторное удаление не считается ошибкой.
+func (s *MemoryStorage) DeleteComment(ctx context.Context, commentID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.comments[commentID]
+	if !ok {
+		return false, ErrCommentNotFound
+	}
+	if record.deletedAt != nil {
+		return false, nil
+	}
+
+	now := s.now()
+	record.deletedAt = &now
+	record.comment.UpdatedAt = now
+	s.appendOutbox(commentEvents(ctx, models.EventCommentDeleted, record.view(), false))
+
+	s.log.InfoContext(ctx, "comment deleted successfully", "commentID", commentID)
+	return true, nil
+}
+
+// GetCommentRevisions возвращает историю правок комментария от старых к новым
+func (s *MemoryStorage) GetCommentRevisions(ctx context.Context, commentID int) ([]models.CommentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.comments[commentID]
+	if !ok {
+		return nil, ErrCommentNotFound
+	}
+	return s.visibleRevisions(record), nil
+}
+
+// GetCommentRevisionsByIDs возвращает истории правок нескольких комментариев
+func (s *MemoryStorage) GetCommentRevisionsByIDs(ctx context.Context, commentIDs []int) (map[int][]models.CommentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[int][]models.CommentRevision, len(commentIDs))
+	for _, id := range commentIDs {
+		if record, ok := s.comments[id]; ok {
+			result[id] = s.visibleRevisions(record)
+		}
+	}
+	return result, nil
+}
+
+// visibleRevisions возвращает копию истории правок; у удаленного комментария,
+// как и его текст, история не выдается
+func (s *MemoryStorage) visibleRevisions(record *memoryComment) []models.CommentRevision {
+	revisions := make([]models.CommentRevision, 0)
+	if record.deletedAt != nil {
+		return revisions
+	}
+	return append(revisions, s.revisions[record.comment.CommentID]...)
+}
+
+// SetReaction ставит или меняет реакцию пользователя
+func (s *MemoryStorage) SetReaction(ctx context.Context, commentID int, userID, reaction string) (models.ReactionSummary, error) {
+	return s.changeReaction(commentID, userID, reaction)
+}
+
+// RemoveReaction снимает реакцию пользователя
+func (s *MemoryStorage) RemoveReaction(ctx context.Context, commentID int, userID string) (models.ReactionSummary, error) {
+	return s.changeReaction(commentID, userID, "")
+}
+
+// changeReaction заменяет реакцию пользователя на reaction; пустая reaction удаляет голос
+func (s *MemoryStorage) changeReaction(commentID int, userID, reaction string) (models.ReactionSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.comments[commentID]
+	if !ok {
+		return models.ReactionSummary{}, ErrCommentNotFound
+	}
+	if record.deletedAt != nil && reaction != "" {
+		return models.ReactionSummary{}, ErrCommentDeleted
+	}
+
+	deltaUp, deltaDown := reactionDelta(record.reactions[userID], reaction)
+	record.upvotes += deltaUp
+	record.downvotes += deltaDown
+	if reaction == "" {
+		delete(record.reactions, userID)
+	} else {
+		record.reactions[userID] = reaction
+	}
+	return record.summary(userID), nil
+}
+
+// GetReactionSummaries возвращает сводки реакций по ID комментариев
+func (s *MemoryStorage) GetReactionSummaries(ctx context.Context, commentIDs []int, userID string) (map[int]models.ReactionSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[int]models.ReactionSummary, len(commentIDs))
+	for _, id := range commentIDs {
+		if record, ok := s.comments[id]; ok {
+			result[id] = record.summary(userID)
+		}
+	}
+	return result, nil
+}
+
+// RelayOutbox передает publish до limit неопубликованных событий в порядке записи
+func (s *MemoryStorage) RelayOutbox(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	if !s.relayMu.TryLock() {
+		return 0, nil
+	}
+	defer s.relayMu.Unlock()
+
+	s.mu.RLock()
+	var events []models.OutboxEvent
+	for _, event := range s.outbox {
+		if len(events) == limit {
+			break
+		}
+		if _, ok := s.published[event.ID]; !ok {
+			events = append(events, event)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	published := publish(ctx, events)
+
+	s.mu.Lock()
+	now := s.now()
+	for _, id := range published {
+		s.published[id] = now
+	}
+	s.mu.Unlock()
+
+	return len(events), nil
+}
+
+// PruneOutbox удаляет события, опубликованные раньше before
+func (s *MemoryStorage) PruneOutbox(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.outbox[:0]
+	for _, event := range s.outbox {
+		if publishedAt, ok := s.published[event.ID]; ok && publishedAt.Before(before) {
+			delete(s.published, event.ID)
+			continue
+		}
+		kept = append(kept, event)
+	}
+	pruned := len(s.outbox) - len(kept)
+	clear(s.outbox[len(kept):])
+	s.outbox = kept
+	return pruned, nil
+}
+
+func (s *MemoryStorage) Close() {
+	s.log.Info("memory storage closed")
+}
+
+// newsComments возвращает комментарии новости с оценками в порядке sort.
+// Вызывается под s.mu.
+func (s *MemoryStorage) newsComments(newsID int, sort string, spec sortSpec) []scoredComment {
+	var result []scoredComment
+	for _, record := range s.comments {
+		if record.comment.NewsID == newsID {
+			comment := record.view()
+			result = append(result, scoredComment{
+				comment: comment,
+				score:   commentScore(sort, record.upvotes, record.downvotes, comment.CreatedAt),
+			})
+		}
+	}
+	slices.SortFunc(result, func(a, b scoredComment) int {
+		return a.compare(spec, &cursor{CreatedAt: b.comment.CreatedAt, ID: b.comment.CommentID, Score: b.score})
+	})
+	return result
+}
+
+// scoredComment комментарий с оценкой для порядка выборки
+type scoredComment struct {
+	comment models.Comment
+	score   float64
+}
+
+// compare сравнивает комментарий с позицией курсора в порядке spec:
+// отрицательный результат, если комментарий идет раньше позиции
+func (c scoredComment) compare(spec sortSpec, position *cursor) int {
+	var result int
+	if spec.score == "" {
+		result = compareKey(c.comment, position.CreatedAt, position.ID)
+	} else if result = cmp.Compare(c.score, position.Score); result == 0 {
+		result = cmp.Compare(c.comment.CommentID, position.ID)
+	}
+	if spec.desc {
+		return -result
+	}
+	return result
+}
+
+// appendOutbox записывает события в outbox. Вызывается под s.mu.
+func (s *MemoryStorage) appendOutbox(events []models.CommentEvent) {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			s.log.Error("failed to encode comment event", "type", event.Type, "error", err)
+			continue
+		}
+		s.nextEventID++
+		s.outbox = append(s.outbox, models.OutboxEvent{
+			ID:        s.nextEventID,
+			Type:      event.Type,
+			NewsID:    event.NewsID,
+			Payload:   payload,
+			CreatedAt: event.OccurredAt,
+		})
+	}
+}
+
+// compareComments упорядочивает комментарии по (created_at, id)
+func compareComments(a, b models.Comment) int {
+	return compareKey(a, b.CreatedAt, b.CommentID)
+}
+
+// compareKey сравнивает комментарий с ключом (createdAt, id)
+func compareKey(comment models.Comment, createdAt time.Time, id int) int {
+	if c := comment.CreatedAt.Compare(createdAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(comment.CommentID, id)
+}
+
+// MemoryNewsStorage реализация NewsStorage в памяти. Пока не добавлено ни
+// одной новости, существующей считается любая новость с положительным ID.
+type MemoryNewsStorage struct {
+	mu   sync.RWMutex
+	news map[int]bool
+}
+
+func NewMemoryNewsStorage(newsIDs ...int) *MemoryNewsStorage {
+	s := &MemoryNewsStorage{news: make(map[int]bool)}
+	s.AddNews(newsIDs...)
+	return s
+}
+
+// AddNews отмечает новости как существующие
+func (s *MemoryNewsStorage) AddNews(newsIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range newsIDs {
+		s.news[id] = true
+	}
+}
+
+func (s *MemoryNewsStorage) NewsExists(ctx context.Context, newsID int) (bool, error) {
+	if newsID <= 0 {
+		return false, apperr.Invalid("news_id", "must be positive, got %d", newsID)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.news) == 0 {
+		return true, nil
+	}
+	return s.news[newsID], nil
+}
+
+func (s *MemoryNewsStorage) NewsExistsBatch(ctx context.Context, newsIDs []int) (map[int]bool, error) {
+	result := make(map[int]bool, len(newsIDs))
+	for _, id := range newsIDs {
+		exists, err := s.NewsExists(ctx, id)
+		result[id] = exists && err == nil
+	}
+	return result, nil
+}
+
+func (s *MemoryNewsStorage) Close() {}
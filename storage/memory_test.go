@@ -0,0 +1,21 @@
+package storage_test
+
+import (
+	"commentservice/storage"
+	"commentservice/storage/storagetest"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storagetest.RunCommentsStorage(t, func(t *testing.T) storage.CommentsStorage {
+		return storage.NewMemoryStorage(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+}
+
+func TestMemoryNewsStorage(t *testing.T) {
+	storagetest.RunNewsStorage(t, func(t *testing.T, newsIDs ...int) storage.NewsStorage {
+		return storage.NewMemoryNewsStorage(newsIDs...)
+	})
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comments_news_id_created_at;
+DROP INDEX IF EXISTS idx_comments_parent_id;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS parent_id;
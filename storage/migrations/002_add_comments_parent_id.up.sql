@@ -0,0 +1,5 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_news_id_created_at ON comments(news_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS cens BOOLEAN DEFAULT FALSE NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS cens_reason TEXT DEFAULT '' NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS author_name,
+    DROP COLUMN IF EXISTS author_id;
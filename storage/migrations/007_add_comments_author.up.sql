@@ -0,0 +1,3 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS author_id TEXT DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS author_name TEXT DEFAULT '' NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS downvotes,
+    DROP COLUMN IF EXISTS upvotes;
+
+DROP TABLE IF EXISTS comment_reactions;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS comment_reactions(
+    comment_id BIGINT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    reaction TEXT NOT NULL CHECK (reaction IN ('up', 'down')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS upvotes INTEGER DEFAULT 0 NOT NULL,
+    ADD COLUMN IF NOT EXISTS downvotes INTEGER DEFAULT 0 NOT NULL;
@@ -0,0 +1,3 @@
+DROP FUNCTION IF EXISTS comment_controversy_score(INTEGER, INTEGER);
+DROP FUNCTION IF EXISTS comment_hot_score(INTEGER, INTEGER, TIMESTAMP);
+DROP FUNCTION IF EXISTS comment_wilson_score(INTEGER, INTEGER);
@@ -0,0 +1,22 @@
+-- Оценки для сортировки комментариев. Совпадают с функциями в storage/sort.go.
+
+-- Нижняя граница доверительного интервала Вильсона (z = 1.96) для доли голосов "за"
+CREATE OR REPLACE FUNCTION comment_wilson_score(up INTEGER, down INTEGER) RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE WHEN up + down = 0 THEN 0 ELSE
+        (p + z * z / (2 * n) - z * sqrt((p * (1 - p) + z * z / (4 * n)) / n)) / (1 + z * z / n)
+    END
+    FROM (SELECT up::float8 / NULLIF(up + down, 0) AS p, (up + down)::float8 AS n, 1.96::float8 AS z) AS v;
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Оценка с затуханием по времени: порядок величины счета плюс время создания в 12.5-часовых интервалах
+CREATE OR REPLACE FUNCTION comment_hot_score(up INTEGER, down INTEGER, created_at TIMESTAMP) RETURNS DOUBLE PRECISION AS $$
+    SELECT sign((up - down)::float8) * log(greatest(abs(up - down), 1)::float8)
+        + extract(epoch FROM created_at)::float8 / 45000;
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Спорность: много голосов, поровну разделенных между "за" и "против"
+CREATE OR REPLACE FUNCTION comment_controversy_score(up INTEGER, down INTEGER) RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE WHEN up <= 0 OR down <= 0 THEN 0 ELSE
+        power((up + down)::float8, least(up, down)::float8 / greatest(up, down))
+    END;
+$$ LANGUAGE SQL IMMUTABLE;
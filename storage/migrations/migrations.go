@@ -0,0 +1,8 @@
+// Package migrations содержит SQL миграции базы комментариев, встроенные в
+// бинарный файл. Файлы именуются NNN_описание.up.sql и NNN_описание.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/infrastructure/config"
+	"commentservice/internal/requestid"
+	"commentservice/internal/tracing"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultNewsAPIPath        = "/news/{id}"
+	defaultNewsAPITimeout     = 2 * time.Second
+	defaultNewsCacheTTL       = 5 * time.Minute
+	defaultNewsNegativeTTL    = 30 * time.Second
+	defaultNewsCacheSize      = 10000
+	defaultNewsAPIConcurrency = 8
+)
+
+// newsCacheEntry закэшированный результат проверки существования новости
+type newsCacheEntry struct {
+	exists  bool
+	expires time.Time
+}
+
+// NewsAPIStorage реализация NewsStorage поверх HTTP API newsservice вместо
+// прямого подключения к его базе. Новость существует, если GET по пути
+// news_api.path отвечает 200, и не существует при 404. Положительные и
+// отрицательные ответы кэшируются на разное время; одновременные проверки
+// одной новости объединяются в один запрос. Кэш ограничен news_api.cache_size
+// записями, чтобы запросы к случайным ID не раздували его.
+type NewsAPIStorage struct {
+	baseURL     string
+	path        string
+	client      *http.Client
+	ttl         time.Duration
+	negativeTTL time.Duration
+	cacheSize   int
+	concurrency int
+
+	mu    sync.Mutex
+	cache map[int]newsCacheEntry
+	group singleflight.Group
+	now   func() time.Time
+	log   *slog.Logger
+}
+
+// NewNewsAPIStorage создает NewsStorage по маршруту newsservice из конфигурации
+func NewNewsAPIStorage(route config.Route, cfg config.NewsAPIConfig, log *slog.Logger) (*NewsAPIStorage, error) {
+	if route.BaseURL == "" {
+		return nil, fmt.Errorf("news route %s has empty base_url", route.Name)
+	}
+
+	s := &NewsAPIStorage{
+		baseURL:     strings.TrimRight(route.BaseURL, "/"),
+		path:        cfg.Path,
+		client:      &http.Client{Timeout: time.Duration(cfg.TimeoutMS) * time.Millisecond},
+		ttl:         time.Duration(cfg.CacheTTL) * time.Second,
+		negativeTTL: time.Duration(cfg.NegativeCacheTTL) * time.Second,
+		cacheSize:   cfg.CacheSize,
+		concurrency: cfg.MaxConcurrency,
+		cache:       make(map[int]newsCacheEntry),
+		now:         time.Now,
+		log:         log,
+	}
+	if s.path == "" {
+		s.path = defaultNewsAPIPath
+	}
+	if !strings.Contains(s.path, "{id}") {
+		return nil, fmt.Errorf("news api path %q has no {id} placeholder", s.path)
+	}
+	if s.client.Timeout <= 0 {
+		s.client.Timeout = defaultNewsAPITimeout
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultNewsCacheTTL
+	}
+	if s.negativeTTL <= 0 {
+		s.negativeTTL = defaultNewsNegativeTTL
+	}
+	if s.cacheSize <= 0 {
+		s.cacheSize = defaultNewsCacheSize
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = defaultNewsAPIConcurrency
+	}
+
+	return s, nil
+}
+
+// NewsExists проверяет существование новости, используя кэш
+func (s *NewsAPIStorage) NewsExists(ctx context.Context, newsID int) (bool, error) {
+	if newsID <= 0 {
+		return false, apperr.Invalid("news_id", "must be positive, got %d", newsID)
+	}
+
+	if exists, ok := s.cached(newsID); ok {
+		return exists, nil
+	}
+
+	// Общий запрос не зависит от отмены контекста того, кто его начал, иначе
+	// отмена одного вызова провалила бы всех ожидающих. Запрос ограничен
+	// таймаутом клиента, а каждый вызов ждет его не дольше своего ctx.
+	fetchCtx := context.WithoutCancel(ctx)
+	ch := s.group.DoChan(strconv.Itoa(newsID), func() (any, error) {
+		exists, err := s.fetch(fetchCtx, newsID)
+		if err != nil {
+			return false, err
+		}
+		s.store(newsID, exists)
+		return exists, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return false, fmt.Errorf("failed to check news existence: %w", ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			s.log.ErrorContext(ctx, "failed to check news existence in newsservice", "news_id", newsID, "error", res.Err)
+			return false, fmt.Errorf("failed to check news existence: %w", res.Err)
+		}
+		return res.Val.(bool), nil
+	}
+}
+
+// NewsExistsBatch проверяет существование нескольких новостей. Промахи кэша
+// запрашиваются параллельно, не более news_api.max_concurrency одновременно.
+func (s *NewsAPIStorage) NewsExistsBatch(ctx context.Context, newsIDs []int) (map[int]bool, error) {
+	result := make(map[int]bool, len(newsIDs))
+	var (
+		mu     sync.Mutex
+		misses []int
+	)
+	for _, id := range newsIDs {
+		if _, seen := result[id]; seen {
+			continue
+		}
+		if id <= 0 {
+			result[id] = false
+			continue
+		}
+		exists, ok := s.cached(id)
+		result[id] = exists
+		if !ok {
+			misses = append(misses, id)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.concurrency)
+	for _, id := range misses {
+		g.Go(func() error {
+			exists, err := s.NewsExists(gctx, id)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			result[id] = exists
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Close сбрасывает кэш и закрывает простаивающие соединения
+func (s *NewsAPIStorage) Close() {
+	s.mu.Lock()
+	s.cache = make(map[int]newsCacheEntry)
+	s.mu.Unlock()
+	s.client.CloseIdleConnections()
+}
+
+// cached возвращает результат из кэша, если он еще не устарел
+func (s *NewsAPIStorage) cached(newsID int) (exists bool, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[newsID]
+	if !ok || s.now().After(entry.expires) {
+		return false, false
+	}
+	return entry.exists, true
+}
+
+// store кэширует результат проверки
+func (s *NewsAPIStorage) store(newsID int, exists bool) {
+	ttl := s.ttl
+	if !exists {
+		ttl = s.negativeTTL
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[newsID]; !ok && len(s.cache) >= s.cacheSize {
+		s.evict(now)
+	}
+	s.cache[newsID] = newsCacheEntry{exists: exists, expires: now.Add(ttl)}
+}
+
+// evict освобождает место в заполненном кэше: удаляет устаревшие записи, а
+// если их нет, десятую часть произвольных, чтобы следующий полный проход
+// понадобился не раньше чем через столько же вставок. Вызывается под s.mu.
+func (s *NewsAPIStorage) evict(now time.Time) {
+	for id, entry := range s.cache {
+		if now.After(entry.expires) {
+			delete(s.cache, id)
+		}
+	}
+	for id := range s.cache {
+		if len(s.cache) < s.cacheSize-s.cacheSize/10 {
+			break
+		}
+		delete(s.cache, id)
+	}
+}
+
+// fetch запрашивает новость у newsservice
+func (s *NewsAPIStorage) fetch(ctx context.Context, newsID int) (bool, error) {
+	url := s.baseURL + strings.ReplaceAll(s.path, "{id}", strconv.Itoa(newsID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	requestid.SetHeader(ctx, req.Header)
+	tracing.InjectHTTP(ctx, req.Header)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,185 @@
+package storage_test
+
+import (
+	"commentservice/internal/infrastructure/config"
+	"commentservice/storage"
+	"commentservice/storage/storagetest"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newNewsAPIStorage(t *testing.T, cfg config.NewsAPIConfig, newsIDs ...int) (*storage.NewsAPIStorage, *storagetest.NewsServer) {
+	t.Helper()
+	srv := storagetest.NewNewsServer(newsIDs...)
+	t.Cleanup(srv.Close)
+
+	route := config.Route{Name: "news", BaseURL: srv.URL}
+	s, err := storage.NewNewsAPIStorage(route, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewNewsAPIStorage() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s, srv
+}
+
+func assertRequests(t *testing.T, srv *storagetest.NewsServer, want int64) {
+	t.Helper()
+	if got := srv.Requests(); got != want {
+		t.Fatalf("newsservice requests = %d, want %d", got, want)
+	}
+}
+
+func assertNewsExists(t *testing.T, s *storage.NewsAPIStorage, newsID int, want bool) {
+	t.Helper()
+	exists, err := s.NewsExists(context.Background(), newsID)
+	if err != nil {
+		t.Fatalf("NewsExists(%d) error = %v", newsID, err)
+	}
+	if exists != want {
+		t.Fatalf("NewsExists(%d) = %v, want %v", newsID, exists, want)
+	}
+}
+
+func TestNewsAPIStorageConformance(t *testing.T) {
+	storagetest.RunNewsStorage(t, func(t *testing.T, newsIDs ...int) storage.NewsStorage {
+		s, _ := newNewsAPIStorage(t, config.NewsAPIConfig{}, newsIDs...)
+		return s
+	})
+}
+
+func TestNewsAPIStorageCache(t *testing.T) {
+	s, srv := newNewsAPIStorage(t, config.NewsAPIConfig{}, 1)
+
+	assertNewsExists(t, s, 1, true)
+	assertNewsExists(t, s, 1, true)
+	assertRequests(t, srv, 1)
+
+	if _, err := s.NewsExists(context.Background(), 0); err == nil {
+		t.Fatal("NewsExists(0) error = nil, want error")
+	}
+	assertRequests(t, srv, 1)
+}
+
+func TestNewsAPIStorageNegativeCache(t *testing.T) {
+	s, srv := newNewsAPIStorage(t, config.NewsAPIConfig{CacheTTL: 60, NegativeCacheTTL: 1})
+	now := time.Now()
+	s.SetNow(func() time.Time { return now })
+
+	assertNewsExists(t, s, 7, false)
+	srv.AddNews(7)
+	assertNewsExists(t, s, 7, false)
+	assertRequests(t, srv, 1)
+
+	now = now.Add(1100 * time.Millisecond)
+	assertNewsExists(t, s, 7, true)
+	assertRequests(t, srv, 2)
+
+	// найденная новость живет в кэше дольше отрицательного ответа
+	now = now.Add(1100 * time.Millisecond)
+	assertNewsExists(t, s, 7, true)
+	assertRequests(t, srv, 2)
+}
+
+func TestNewsAPIStorageCacheSize(t *testing.T) {
+	s, srv := newNewsAPIStorage(t, config.NewsAPIConfig{CacheTTL: 60, NegativeCacheTTL: 1, CacheSize: 10}, 1)
+	now := time.Now()
+	s.SetNow(func() time.Time { return now })
+
+	// случайные ID не раздувают кэш
+	for id := 1; id <= 100; id++ {
+		assertNewsExists(t, s, id, id == 1)
+		if got := s.CacheLen(); got > 10 {
+			t.Fatalf("cache size after news %d = %d, want at most 10", id, got)
+		}
+	}
+
+	// устаревшие записи вытесняются первыми
+	for id := 101; id <= 109; id++ {
+		assertNewsExists(t, s, id, false)
+	}
+	assertNewsExists(t, s, 1, true)
+	now = now.Add(1100 * time.Millisecond)
+	requests := srv.Requests()
+	assertNewsExists(t, s, 200, false)
+	assertNewsExists(t, s, 1, true)
+	assertRequests(t, srv, requests+1)
+	if got := s.CacheLen(); got != 2 {
+		t.Errorf("cache size after sweep = %d, want 2", got)
+	}
+}
+
+func TestNewsAPIStorageBatch(t *testing.T) {
+	s, srv := newNewsAPIStorage(t, config.NewsAPIConfig{MaxConcurrency: 2}, 1, 2)
+	ctx := context.Background()
+
+	assertNewsExists(t, s, 1, true)
+	assertRequests(t, srv, 1)
+
+	got, err := s.NewsExistsBatch(ctx, []int{1, 2, 3, 2, -1, 3})
+	if err != nil {
+		t.Fatalf("NewsExistsBatch() error = %v", err)
+	}
+	want := map[int]bool{1: true, 2: true, 3: false, -1: false}
+	if !maps.Equal(got, want) {
+		t.Fatalf("NewsExistsBatch() = %v, want %v", got, want)
+	}
+	// 1 уже в кэше, дубликаты и некорректные id не запрашиваются
+	assertRequests(t, srv, 3)
+
+	if _, err := s.NewsExistsBatch(ctx, []int{1, 2, 3}); err != nil {
+		t.Fatalf("NewsExistsBatch() error = %v", err)
+	}
+	assertRequests(t, srv, 3)
+}
+
+func TestNewsAPIStorageUpstreamError(t *testing.T) {
+	s, srv := newNewsAPIStorage(t, config.NewsAPIConfig{}, 1, 2, 3)
+	ctx := context.Background()
+
+	srv.Fail(http.StatusInternalServerError)
+	if _, err := s.NewsExists(ctx, 1); err == nil {
+		t.Fatal("NewsExists() error = nil, want error")
+	}
+	// После первой ошибки пакет не ждет остальных запросов, они доживают в
+	// фоне, поэтому дальше проверяется только новость 1
+	if _, err := s.NewsExistsBatch(ctx, []int{2, 3}); err == nil {
+		t.Fatal("NewsExistsBatch() error = nil, want error")
+	}
+
+	// ошибки не кэшируются
+	srv.Fail(0)
+	requests := srv.Requests()
+	assertNewsExists(t, s, 1, true)
+	if got := srv.Requests(); got <= requests {
+		t.Fatalf("newsservice requests = %d, want more than %d", got, requests)
+	}
+}
+
+func TestNewsAPIStorageCanceledCallerKeepsFetch(t *testing.T) {
+	s, srv := newNewsAPIStorage(t, config.NewsAPIConfig{}, 1)
+	arrived, release := srv.Block()
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.NewsExists(ctx, 1)
+		done <- err
+	}()
+	<-arrived
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("NewsExists() error = %v, want %v", err, context.Canceled)
+	}
+
+	// общий запрос продолжается после отмены и его результат получают остальные
+	release()
+	assertNewsExists(t, s, 1, true)
+	assertRequests(t, srv, 1)
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"commentservice/internal/models"
+	"commentservice/internal/requestid"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// outboxLockKey ключ advisory-блокировки, под которой работает единственный
+// relay. Без нее события одной новости из разных реплик могли бы обгонять друг друга.
+const outboxLockKey = 7_011_001
+
+// PublishFunc публикует события и возвращает ID успешно опубликованных
+type PublishFunc func(ctx context.Context, events []models.OutboxEvent) []int64
+
+// commentEvents формирует события для сохраненного комментария. При
+// выставленном признаке цензуры к основному событию добавляется comment.censored.
+// ID запроса из ctx сохраняется в событии, чтобы связать его с исходным запросом.
+func commentEvents(ctx context.Context, eventType string, comment models.Comment, censored bool) []models.CommentEvent {
+	now := time.Now()
+	requestID := requestid.FromContext(ctx)
+	events := []models.CommentEvent{{
+		Type:       eventType,
+		NewsID:     comment.NewsID,
+		CommentID:  comment.CommentID,
+		Comment:    &comment,
+		RequestID:  requestID,
+		OccurredAt: now,
+	}}
+	if censored {
+		events = append(events, models.CommentEvent{
+			Type:       models.EventCommentCensored,
+			NewsID:     comment.NewsID,
+			CommentID:  comment.CommentID,
+			Comment:    &comment,
+			RequestID:  requestID,
+			OccurredAt: now,
+		})
+	}
+	return events
+}
+
+// insertOutbox записывает события в outbox в рамках транзакции изменения комментария
+func insertOutbox(ctx context.Context, tx pgx.Tx, events []models.CommentEvent) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO outbox (event_type, news_id, payload, created_at)
+			VALUES ($1, $2, $3, $4);`,
+			event.Type, event.NewsID, payload, event.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("failed to save %s event: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// RelayOutbox выбирает до limit неопубликованных событий в порядке записи,
+// передает их publish и отмечает опубликованными вернувшиеся ID. Если outbox
+// уже обрабатывает другой экземпляр сервиса, возвращает 0 без ошибки.
+func (s *Storage) RelayOutbox(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	defer s.observe("RelayOutbox", time.Now())
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1);`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to acquire outbox lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, event_type, news_id, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1;`,
+		limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch outbox: %w", err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OutboxEvent, error) {
+		var event models.OutboxEvent
+		err := row.Scan(&event.ID, &event.Type, &event.NewsID, &event.Payload, &event.CreatedAt)
+		return event, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan outbox: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	published := publish(ctx, events)
+	if len(published) > 0 {
+		_, err = tx.Exec(ctx,
+			`UPDATE outbox SET published_at = $2 WHERE id = ANY($1);`,
+			published, time.Now())
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark outbox events published: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(events), nil
+}
+
+// PruneOutbox удаляет опубликованные раньше before события, чтобы таблица
+// outbox не росла бесконечно. Неопубликованные события не трогаются.
+func (s *Storage) PruneOutbox(ctx context.Context, before time.Time) (int, error) {
+	defer s.observe("PruneOutbox", time.Now())
+
+	tag, err := s.db.Exec(ctx,
+		`DELETE FROM outbox WHERE published_at < $1;`,
+		before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune outbox: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
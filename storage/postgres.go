@@ -1,19 +1,29 @@
 package storage
 
 import (
+	"commentservice/internal/apperr"
 	"commentservice/internal/infrastructure/config"
 	"commentservice/internal/models"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Storage struct {
-	db  *pgxpool.Pool
-	log *slog.Logger
+	db       *pgxpool.Pool
+	name     string
+	log      *slog.Logger
+	observer QueryObserver
+}
+
+// QueryObserver получает длительность выполнения методов хранилища, например для метрик
+type QueryObserver interface {
+	ObserveQuery(method string, duration time.Duration)
 }
 
 // newStorage внутренняя функция создания хранилища
@@ -24,7 +34,13 @@ func newStorage(dbConfig config.DBConfig, dbName string, log *slog.Logger) (*Sto
 		"dsn", fmt.Sprintf("postgres://%s:***@%s:%d/%s",
 			dbConfig.UserName, dbConfig.Host, dbConfig.Port, dbConfig.DBName))
 
-	db, err := pgxpool.New(context.Background(), connStr)
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config of database %s: %w", dbName, err)
+	}
+	poolConfig.ConnConfig.Tracer = queryTracer{dbName: dbConfig.DBName}
+
+	db, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection to database %s: %w", dbName, err)
 	}
@@ -40,8 +56,9 @@ func newStorage(dbConfig config.DBConfig, dbName string, log *slog.Logger) (*Sto
 		"database", dbConfig.DBName)
 
 	return &Storage{
-		db:  db,
-		log: log,
+		db:   db,
+		name: dbName,
+		log:  log,
 	}, nil
 }
 
@@ -116,62 +133,571 @@ func NewNewsStorage(cfg *config.Config, log *slog.Logger) (*Storage, error) {
 // 	}, nil
 // }
 
-// AddComment добавляет комментарий в БД
-func (s *Storage) AddComment(ctx context.Context, newsID int, comment string) error {
-	_, err := s.db.Exec(ctx, `INSERT INTO comments (news_id, message, created_at, updated_at)
-	VALUES ($1, $2, $3, $4)`, newsID, comment, time.Now(), time.Now())
+// AddComment добавляет комментарий в БД и возвращает его с присвоенным ID
+func (s *Storage) AddComment(ctx context.Context, comment models.Comment) (models.Comment, error) {
+	defer s.observe("AddComment", time.Now())
+	if err := ValidateContent(comment.Content); err != nil {
+		return models.Comment{}, err
+	}
+
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		s.log.Error("failed to save comment to database", "newsID", newsID, "error", err)
-		return fmt.Errorf("failed to save comment: %w", err)
+		return models.Comment{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	err = tx.QueryRow(ctx, `INSERT INTO comments (news_id, parent_id, content, cens, cens_reason, author_id, author_name, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at, updated_at`,
+		comment.NewsID, comment.ParentID, comment.Content, comment.Cens, comment.CensReason,
+		comment.AuthorID, comment.AuthorName, now, now,
+	).Scan(&comment.CommentID, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to save comment to database", "newsID", comment.NewsID, "error", err)
+		return models.Comment{}, fmt.Errorf("failed to save comment: %w", err)
+	}
+
+	if err := insertOutbox(ctx, tx, commentEvents(ctx, models.EventCommentCreated, comment, comment.Cens)); err != nil {
+		s.log.ErrorContext(ctx, "failed to save comment event", "newsID", comment.NewsID, "error", err)
+		return models.Comment{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Comment{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	s.log.Info("comment added successfully", "newsID", newsID)
-	return nil
+	s.log.InfoContext(ctx, "comment added successfully", "newsID", comment.NewsID, "commentID", comment.CommentID)
+	return comment, nil
 }
 
-// GetComments получает список комментариев по ID новости
-func (s *Storage) GetComments(ctx context.Context, newsID int) ([]models.Comment, error) {
-	if newsID < 1 {
-		err := fmt.Errorf("invalid news ID: %d", newsID)
-		s.log.Error("Invalid news ID", "newsID", newsID, "error", err)
-		return nil, err
+// GetComment получает комментарий по его ID
+func (s *Storage) GetComment(ctx context.Context, commentID int) (models.Comment, error) {
+	defer s.observe("GetComment", time.Now())
+
+	var comment models.Comment
+	err := s.db.QueryRow(ctx,
+		`SELECT `+commentColumns+`
+		FROM comments c
+		WHERE c.id = $1;`,
+		commentID,
+	).Scan(commentFields(&comment)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Comment{}, ErrCommentNotFound
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment from database", "commentID", commentID, "error", err)
+		return models.Comment{}, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// GetCommentsByIDs получает несколько комментариев одним запросом
+func (s *Storage) GetCommentsByIDs(ctx context.Context, commentIDs []int) (map[int]models.Comment, error) {
+	defer s.observe("GetCommentsByIDs", time.Now())
+
+	result := make(map[int]models.Comment, len(commentIDs))
+	if len(commentIDs) == 0 {
+		return result, nil
 	}
 
 	rows, err := s.db.Query(ctx,
-		`SELECT id, news_id, content, created_at
-		FROM comments
-		WHERE news_id = $1
-		ORDER BY created_at;`,
-		newsID)
+		`SELECT `+commentColumns+`
+		FROM comments c
+		WHERE c.id = ANY($1);`,
+		commentIDs,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comments from database", "commentIDs", commentIDs, "error", err)
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		result[comment.CommentID] = comment
+	}
+
+	return result, nil
+}
+
+// GetComments получает страницу комментариев по ID новости в порядке
+// query.Sort. Без курсора используется Offset, с курсором - выборка по ключу
+// сортировки: (created_at, id) или (оценка, id).
+func (s *Storage) GetComments(ctx context.Context, query models.CommentQuery) (models.CommentPage, error) {
+	defer s.observe("GetComments", time.Now())
+
+	newsID := query.NewsID
+	if newsID < 1 {
+		err := apperr.Invalid("news_id", "must be positive, got %d", newsID)
+		s.log.ErrorContext(ctx, "Invalid news ID", "newsID", newsID, "error", err)
+		return models.CommentPage{}, err
+	}
+	if query.Limit < 1 {
+		return models.CommentPage{}, apperr.Invalid("limit", "must be positive, got %d", query.Limit)
+	}
+	if query.Offset < 0 {
+		return models.CommentPage{}, apperr.Invalid("offset", "must not be negative, got %d", query.Offset)
+	}
+	sort := normalizeSort(query.Sort)
+	spec, ok := sortSpecs[sort]
+	if !ok {
+		return models.CommentPage{}, apperr.Invalid("sort", "unknown sort order %q", query.Sort)
+	}
+
+	var after *cursor
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor, sort)
+		if err != nil {
+			return models.CommentPage{}, err
+		}
+		after = &c
+	}
+
+	// Ключ сортировки и оценка, по которой строится курсор
+	key, score := "c.created_at", "0::float8"
+	if spec.score != "" {
+		key, score = spec.score, spec.score
+	}
+	desc := spec.desc
+	if after != nil && after.Backward {
+		desc = !desc
+	}
+	direction, compare := "", ">"
+	if desc {
+		direction, compare = " DESC", "<"
+	}
+
+	columns := `SELECT ` + commentColumns + `, ` + score + ` FROM comments c`
+	order := fmt.Sprintf(`ORDER BY %s%s, c.id%s`, key, direction, direction)
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	switch {
+	case after == nil:
+		rows, err = s.db.Query(ctx, columns+`
+			WHERE c.news_id = $1
+			`+order+`
+			LIMIT $2 OFFSET $3;`,
+			newsID, query.Limit+1, query.Offset)
+	default:
+		var position any = after.CreatedAt
+		if spec.score != "" {
+			position = after.Score
+		}
+		rows, err = s.db.Query(ctx, columns+`
+			WHERE c.news_id = $1 AND (`+key+`, c.id) `+compare+` ($2, $3)
+			`+order+`
+			LIMIT $4;`,
+			newsID, position, after.ID, query.Limit+1)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comments from database", "newsID", newsID, "error", err)
+		return models.CommentPage{}, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	comments, scores, err := scanScoredComments(rows)
 	if err != nil {
-		s.log.Error("failed to get comments from database", "newsID", newsID, "error", err)
-		return []models.Comment{}, fmt.Errorf("failed to get comments: %w", err)
+		s.log.ErrorContext(ctx, "failed to scan row", "newsID", newsID, "error", err)
+		return models.CommentPage{}, err
 	}
+
+	page := buildPage(comments, scores, sort, query.Limit, after, query.Offset)
+
+	err = s.db.QueryRow(ctx, `SELECT COUNT(*) FROM comments WHERE news_id = $1;`, newsID).Scan(&page.Total)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to count comments", "newsID", newsID, "error", err)
+		return models.CommentPage{}, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	return page, nil
+}
+
+// scanScoredComments читает строки выборки комментариев с оценкой в
+// последней колонке и закрывает rows
+func scanScoredComments(rows pgx.Rows) ([]models.Comment, []float64, error) {
+	defer rows.Close()
+
+	var (
+		comments []models.Comment
+		scores   []float64
+	)
+	for rows.Next() {
+		var (
+			comment models.Comment
+			score   float64
+		)
+		if err := rows.Scan(append(commentFields(&comment), &score)...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		comments = append(comments, comment)
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return comments, scores, nil
+}
+
+// commentColumns список колонок комментария для выборок из таблицы comments
+// с псевдонимом c. Текст удаленного комментария не выдается.
+const commentColumns = `c.id, c.news_id, c.parent_id,
+	CASE WHEN c.deleted_at IS NULL THEN c.content ELSE '' END,
+	c.created_at, c.updated_at, c.deleted_at IS NOT NULL, c.cens, c.cens_reason,
+	c.author_id, c.author_name`
+
+// commentFields возвращает приемники Scan в порядке commentColumns
+func commentFields(comment *models.Comment) []any {
+	return []any{
+		&comment.CommentID,
+		&comment.NewsID,
+		&comment.ParentID,
+		&comment.Content,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.Deleted,
+		&comment.Cens,
+		&comment.CensReason,
+		&comment.AuthorID,
+		&comment.AuthorName,
+	}
+}
+
+// scanComments читает строки выборки комментариев и закрывает rows
+func scanComments(rows pgx.Rows) ([]models.Comment, error) {
 	defer rows.Close()
 
 	var comments []models.Comment
 	for rows.Next() {
 		var comment models.Comment
-		err = rows.Scan(
-			&comment.CommentID,
-			&comment.NewsID,
-			&comment.Content,
-			&comment.CreatedAt,
-		)
+		err := rows.Scan(commentFields(&comment)...)
 		if err != nil {
-			s.log.Error("failed to scan row", "newsID", newsID, "error", err)
-			return []models.Comment{}, fmt.Errorf("failed to scan row: %w", err)
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		comments = append(comments, comment)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
 
 	return comments, nil
 }
 
+// GetCommentThread получает комментарии новости вместе с ответами не глубже
+// query.MaxDepth уровней. Limit и Offset применяются к корневым комментариям
+// в самом запросе. Результат плоский и упорядочен по глубине и дате создания,
+// для каждого узла заполнены Depth и ReplyCount.
+func (s *Storage) GetCommentThread(ctx context.Context, query models.ThreadQuery) ([]models.CommentNode, int, error) {
+	defer s.observe("GetCommentThread", time.Now())
+
+	newsID := query.NewsID
+	if newsID < 1 {
+		err := apperr.Invalid("news_id", "must be positive, got %d", newsID)
+		s.log.ErrorContext(ctx, "Invalid news ID", "newsID", newsID, "error", err)
+		return nil, 0, err
+	}
+
+	var total int
+	err := s.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM comments WHERE news_id = $1 AND parent_id IS NULL;`,
+		newsID,
+	).Scan(&total)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to count root comments", "newsID", newsID, "error", err)
+		return nil, 0, fmt.Errorf("failed to count root comments: %w", err)
+	}
+
+	// LIMIT NULL в Postgres означает отсутствие ограничения
+	var limit *int
+	if query.Limit > 0 {
+		limit = &query.Limit
+	}
+
+	rows, err := s.db.Query(ctx,
+		`WITH RECURSIVE roots AS (
+			SELECT id
+			FROM comments
+			WHERE news_id = $1 AND parent_id IS NULL
+			ORDER BY created_at, id
+			LIMIT $3 OFFSET $4
+		), thread AS (
+			SELECT id, 0 AS depth
+			FROM roots
+			UNION ALL
+			SELECT r.id, t.depth + 1
+			FROM comments r
+			JOIN thread t ON r.parent_id = t.id
+			WHERE t.depth < $2
+		)
+		SELECT `+commentColumns+`, t.depth,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count
+		FROM thread t
+		JOIN comments c ON c.id = t.id
+		ORDER BY t.depth, c.created_at, c.id;`,
+		newsID, query.MaxDepth, limit, query.Offset)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment thread from database", "newsID", newsID, "error", err)
+		return nil, 0, fmt.Errorf("failed to get comment thread: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []models.CommentNode
+	for rows.Next() {
+		var node models.CommentNode
+		err = rows.Scan(append(commentFields(&node.Comment), &node.Depth, &node.ReplyCount)...)
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to scan row", "newsID", newsID, "error", err)
+			return nil, 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read comment thread: %w", err)
+	}
+
+	return nodes, total, nil
+}
+
+// UpdateComment заменяет текст и признаки цензуры комментария comment.CommentID,
+// сохраняя предыдущий текст в comment_revisions в той же транзакции
+func (s *Storage) UpdateComment(ctx context.Context, comment models.Comment) (models.Comment, bool, error) {
+	defer s.observe("UpdateComment", time.Now())
+	if err := ValidateContent(comment.Content); err != nil {
+		return models.Comment{}, false, err
+	}
+
+	commentID := comment.CommentID
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Comment{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		oldContent string
+		deleted    bool
+		wasCens    bool
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT content, deleted_at IS NOT NULL, cens
+		FROM comments
+		WHERE id = $1
+		FOR UPDATE;`,
+		commentID,
+	).Scan(&oldContent, &deleted, &wasCens)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Comment{}, false, ErrCommentNotFound
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to lock comment", "commentID", commentID, "error", err)
+		return models.Comment{}, false, fmt.Errorf("failed to get comment: %w", err)
+	}
+	if deleted {
+		return models.Comment{}, false, ErrCommentDeleted
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(ctx,
+		`INSERT INTO comment_revisions (comment_id, content, created_at)
+		VALUES ($1, $2, $3);`,
+		commentID, oldContent, now)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to save comment revision", "commentID", commentID, "error", err)
+		return models.Comment{}, false, fmt.Errorf("failed to save comment revision: %w", err)
+	}
+
+	var updated models.Comment
+	err = tx.QueryRow(ctx,
+		`UPDATE comments c
+		SET content = $2, cens = $3, cens_reason = $4, updated_at = $5
+		WHERE c.id = $1
+		RETURNING `+commentColumns+`;`,
+		commentID, comment.Content, comment.Cens, comment.CensReason, now,
+	).Scan(commentFields(&updated)...)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to update comment", "commentID", commentID, "error", err)
+		return models.Comment{}, false, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	events := commentEvents(ctx, models.EventCommentUpdated, updated, updated.Cens && !wasCens)
+	if err := insertOutbox(ctx, tx, events); err != nil {
+		s.log.ErrorContext(ctx, "failed to save comment event", "commentID", commentID, "error", err)
+		return models.Comment{}, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Comment{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.InfoContext(ctx, "comment updated successfully", "newsID", updated.NewsID, "commentID", commentID)
+	return updated, wasCens, nil
+}
+
+// DeleteComment мягко удаляет комментарий: строка остается, чтобы не рвать
+// ветку ответов. Повторное удаление не считается ошибкой и не порождает событий.
+func (s *Storage) DeleteComment(ctx context.Context, commentID int) (bool, error) {
+	defer s.observe("DeleteComment", time.Now())
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var comment models.Comment
+	now := time.Now()
+	err = tx.QueryRow(ctx,
+		`UPDATE comments c
+		SET deleted_at = $2, updated_at = $2
+		WHERE c.id = $1 AND c.deleted_at IS NULL
+		RETURNING `+commentColumns+`;`,
+		commentID, now,
+	).Scan(commentFields(&comment)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1);`, commentID).Scan(&exists)
+		if err != nil {
+			return false, fmt.Errorf("failed to check comment existence: %w", err)
+		}
+		if !exists {
+			return false, ErrCommentNotFound
+		}
+		return false, nil
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to delete comment", "commentID", commentID, "error", err)
+		return false, fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	if err := insertOutbox(ctx, tx, commentEvents(ctx, models.EventCommentDeleted, comment, false)); err != nil {
+		s.log.ErrorContext(ctx, "failed to save comment event", "commentID", commentID, "error", err)
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.InfoContext(ctx, "comment deleted successfully", "commentID", commentID)
+	return true, nil
+}
+
+// GetCommentRevisions возвращает историю правок комментария от старых к новым
+func (s *Storage) GetCommentRevisions(ctx context.Context, commentID int) ([]models.CommentRevision, error) {
+	defer s.observe("GetCommentRevisions", time.Now())
+
+	var deleted bool
+	err := s.db.QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM comments WHERE id = $1;`, commentID).Scan(&deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to check comment existence", "commentID", commentID, "error", err)
+		return nil, fmt.Errorf("failed to check comment existence: %w", err)
+	}
+	// Как и текст удаленного комментария, его прежние версии не выдаются
+	if deleted {
+		return make([]models.CommentRevision, 0), nil
+	}
+
+	rows, err := s.db.Query(ctx,
+		`SELECT id, comment_id, content, created_at
+		FROM comment_revisions
+		WHERE comment_id = $1
+		ORDER BY created_at, id;`,
+		commentID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment revisions", "commentID", commentID, "error", err)
+		return nil, fmt.Errorf("failed to get comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]models.CommentRevision, 0)
+	for rows.Next() {
+		var revision models.CommentRevision
+		err := rows.Scan(
+			&revision.RevisionID,
+			&revision.CommentID,
+			&revision.Content,
+			&revision.EditedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read comment revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetCommentRevisionsByIDs получает истории правок нескольких комментариев одним запросом
+func (s *Storage) GetCommentRevisionsByIDs(ctx context.Context, commentIDs []int) (map[int][]models.CommentRevision, error) {
+	defer s.observe("GetCommentRevisionsByIDs", time.Now())
+
+	result := make(map[int][]models.CommentRevision, len(commentIDs))
+	if len(commentIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := s.db.Query(ctx,
+		`SELECT c.id, r.id, r.content, r.created_at
+		FROM comments c
+		LEFT JOIN comment_revisions r ON r.comment_id = c.id AND c.deleted_at IS NULL
+		WHERE c.id = ANY($1)
+		ORDER BY c.id, r.created_at, r.id;`,
+		commentIDs)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get comment revisions", "commentIDs", commentIDs, "error", err)
+		return nil, fmt.Errorf("failed to get comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			commentID  int
+			revisionID *int
+			content    *string
+			editedAt   *time.Time
+		)
+		if err := rows.Scan(&commentID, &revisionID, &content, &editedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		revisions, ok := result[commentID]
+		if !ok {
+			revisions = make([]models.CommentRevision, 0)
+		}
+		// У комментария без правок LEFT JOIN дает одну строку с NULL
+		if revisionID != nil {
+			revisions = append(revisions, models.CommentRevision{
+				RevisionID: *revisionID,
+				CommentID:  commentID,
+				Content:    *content,
+				EditedAt:   *editedAt,
+			})
+		}
+		result[commentID] = revisions
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read comment revisions: %w", err)
+	}
+
+	return result, nil
+}
+
 func (s *Storage) NewsExists(ctx context.Context, id int) (bool, error) {
+	defer s.observe("NewsExists", time.Now())
+
 	if id <= 0 {
-		return false, fmt.Errorf("invalid news ID: %d", id)
+		return false, apperr.Invalid("news_id", "must be positive, got %d", id)
 	}
 
 	var exists bool
@@ -180,17 +706,72 @@ func (s *Storage) NewsExists(ctx context.Context, id int) (bool, error) {
 
 	err := s.db.QueryRow(ctx, query, id).Scan(&exists)
 	if err != nil {
-		s.log.Error("failed to check news existence in database", "news_id", id, "error", err)
+		s.log.ErrorContext(ctx, "failed to check news existence in database", "news_id", id, "error", err)
 		return false, fmt.Errorf("failed to check news existence: %w", err)
 	}
 
 	return exists, nil
 }
 
+// NewsExistsBatch проверяет существование нескольких новостей одним запросом
+func (s *Storage) NewsExistsBatch(ctx context.Context, ids []int) (map[int]bool, error) {
+	defer s.observe("NewsExistsBatch", time.Now())
+
+	result := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		result[id] = false
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	rows, err := s.db.Query(ctx, `SELECT id FROM news WHERE id = ANY($1)`, ids)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to check news existence in database", "news_ids", ids, "error", err)
+		return nil, fmt.Errorf("failed to check news existence: %w", err)
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan news ids: %w", err)
+	}
+	for _, id := range existing {
+		result[id] = true
+	}
+
+	return result, nil
+}
+
+// SetQueryObserver задает получателя длительностей методов. Вызывается до начала работы.
+func (s *Storage) SetQueryObserver(observer QueryObserver) {
+	s.observer = observer
+}
+
+// observe передает длительность метода, начатого в start
+func (s *Storage) observe(method string, start time.Time) {
+	if s.observer != nil {
+		s.observer.ObserveQuery(method, time.Since(start))
+	}
+}
+
+// Name возвращает имя базы: comments или news
+func (s *Storage) Name() string {
+	return s.name
+}
+
+// Stat возвращает статистику пула соединений
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.db.Stat()
+}
+
+// Ping проверяет доступность базы данных
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
 func (s *Storage) Close() {
 	if s.db != nil {
 		s.db.Close()
 		s.log.Info("database connection closed",
-			"database", "comments/news")
+			"database", s.name)
 	}
 }
@@ -0,0 +1,114 @@
+package storage_test
+
+import (
+	"commentservice/storage"
+	"commentservice/storage/storagetest"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresDSNEnv переменная окружения с DSN тестовой базы Postgres. Без нее
+// проверки Postgres-хранилища пропускаются.
+const postgresDSNEnv = "COMMENTS_TEST_POSTGRES_DSN"
+
+var schemaSeq atomic.Int64
+
+// TestPostgresStorage прогоняет общие проверки над Storage. Каждая проверка
+// получает отдельную пустую схему с примененными миграциями.
+func TestPostgresStorage(t *testing.T) {
+	dsn := postgresDSN(t)
+
+	storagetest.RunCommentsStorage(t, func(t *testing.T) storage.CommentsStorage {
+		s := storage.NewStorageWithPool(newSchemaPool(t, dsn), testLogger())
+		migrator, err := storage.NewMigrator(s)
+		if err != nil {
+			t.Fatalf("NewMigrator() error = %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			s.Close()
+			t.Fatalf("migrator.Up() error = %v", err)
+		}
+		return s
+	})
+}
+
+// TestPostgresNewsStorage прогоняет проверки NewsStorage над таблицей news,
+// созданной в отдельной схеме так же, как в базе newsservice.
+func TestPostgresNewsStorage(t *testing.T) {
+	dsn := postgresDSN(t)
+
+	storagetest.RunNewsStorage(t, func(t *testing.T, newsIDs ...int) storage.NewsStorage {
+		ctx := context.Background()
+		db := newSchemaPool(t, dsn)
+		if _, err := db.Exec(ctx, "CREATE TABLE news (id INT PRIMARY KEY)"); err != nil {
+			db.Close()
+			t.Fatalf("failed to create news table: %v", err)
+		}
+		if _, err := db.Exec(ctx, "INSERT INTO news (id) SELECT unnest($1::int[])", newsIDs); err != nil {
+			db.Close()
+			t.Fatalf("failed to insert news: %v", err)
+		}
+		return storage.NewStorageWithPool(db, testLogger())
+	})
+}
+
+// postgresDSN возвращает DSN тестовой базы или пропускает тест
+func postgresDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+	return dsn
+}
+
+// newSchemaPool создает пустую схему, которая удаляется после проверки, и
+// пул, работающий в ней
+func newSchemaPool(t *testing.T, dsn string) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+	schema := fmt.Sprintf("conformance_%d_%d", os.Getpid(), schemaSeq.Add(1))
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", postgresDSNEnv, err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(context.Background(), dsn)
+		if err != nil {
+			t.Errorf("failed to connect to drop schema %s: %v", schema, err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", postgresDSNEnv, err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	db, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	return db
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
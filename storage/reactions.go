@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"commentservice/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// reactionDelta изменение счетчиков (up, down) при замене реакции old на
+// reaction; пустая строка означает отсутствие реакции
+func reactionDelta(old, reaction string) (up, down int) {
+	count := func(r string) (int, int) {
+		switch r {
+		case models.ReactionUp:
+			return 1, 0
+		case models.ReactionDown:
+			return 0, 1
+		}
+		return 0, 0
+	}
+	oldUp, oldDown := count(old)
+	newUp, newDown := count(reaction)
+	return newUp - oldUp, newDown - oldDown
+}
+
+// newSummary собирает сводку реакций по счетчикам
+func newSummary(up, down int, mine string) models.ReactionSummary {
+	return models.ReactionSummary{Up: up, Down: down, Score: up - down, Mine: mine}
+}
+
+// SetReaction ставит или меняет реакцию пользователя. Голос и счетчики
+// комментария меняются в одной транзакции под блокировкой комментария.
+func (s *Storage) SetReaction(ctx context.Context, commentID int, userID, reaction string) (models.ReactionSummary, error) {
+	defer s.observe("SetReaction", time.Now())
+	return s.changeReaction(ctx, commentID, userID, reaction)
+}
+
+// RemoveReaction снимает реакцию пользователя
+func (s *Storage) RemoveReaction(ctx context.Context, commentID int, userID string) (models.ReactionSummary, error) {
+	defer s.observe("RemoveReaction", time.Now())
+	return s.changeReaction(ctx, commentID, userID, "")
+}
+
+// changeReaction заменяет реакцию пользователя на reaction; пустая reaction
+// удаляет голос
+func (s *Storage) changeReaction(ctx context.Context, commentID int, userID, reaction string) (models.ReactionSummary, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.ReactionSummary{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deleted bool
+	err = tx.QueryRow(ctx,
+		`SELECT deleted_at IS NOT NULL FROM comments WHERE id = $1 FOR UPDATE;`,
+		commentID,
+	).Scan(&deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ReactionSummary{}, ErrCommentNotFound
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to lock comment", "commentID", commentID, "error", err)
+		return models.ReactionSummary{}, fmt.Errorf("failed to get comment: %w", err)
+	}
+	if deleted && reaction != "" {
+		return models.ReactionSummary{}, ErrCommentDeleted
+	}
+
+	var old string
+	err = tx.QueryRow(ctx,
+		`SELECT reaction FROM comment_reactions WHERE comment_id = $1 AND user_id = $2;`,
+		commentID, userID,
+	).Scan(&old)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return models.ReactionSummary{}, fmt.Errorf("failed to get reaction: %w", err)
+	}
+
+	if reaction == "" {
+		_, err = tx.Exec(ctx,
+			`DELETE FROM comment_reactions WHERE comment_id = $1 AND user_id = $2;`,
+			commentID, userID)
+	} else {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO comment_reactions (comment_id, user_id, reaction, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $4)
+			ON CONFLICT (comment_id, user_id) DO UPDATE SET reaction = EXCLUDED.reaction, updated_at = EXCLUDED.updated_at;`,
+			commentID, userID, reaction, time.Now())
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to save reaction", "commentID", commentID, "error", err)
+		return models.ReactionSummary{}, fmt.Errorf("failed to save reaction: %w", err)
+	}
+
+	var up, down int
+	deltaUp, deltaDown := reactionDelta(old, reaction)
+	err = tx.QueryRow(ctx,
+		`UPDATE comments
+		SET upvotes = upvotes + $2, downvotes = downvotes + $3
+		WHERE id = $1
+		RETURNING upvotes, downvotes;`,
+		commentID, deltaUp, deltaDown,
+	).Scan(&up, &down)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to update reaction counters", "commentID", commentID, "error", err)
+		return models.ReactionSummary{}, fmt.Errorf("failed to update reaction counters: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.ReactionSummary{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return newSummary(up, down, reaction), nil
+}
+
+// GetReactionSummaries получает сводки реакций одним запросом
+func (s *Storage) GetReactionSummaries(ctx context.Context, commentIDs []int, userID string) (map[int]models.ReactionSummary, error) {
+	defer s.observe("GetReactionSummaries", time.Now())
+
+	result := make(map[int]models.ReactionSummary, len(commentIDs))
+	if len(commentIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := s.db.Query(ctx,
+		`SELECT c.id, c.upvotes, c.downvotes, COALESCE(r.reaction, '')
+		FROM comments c
+		LEFT JOIN comment_reactions r ON r.comment_id = c.id AND r.user_id = $2
+		WHERE c.id = ANY($1);`,
+		commentIDs, userID,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get reaction summaries", "commentIDs", commentIDs, "error", err)
+		return nil, fmt.Errorf("failed to get reaction summaries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, up, down int
+			mine         string
+		)
+		if err := rows.Scan(&id, &up, &down, &mine); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		result[id] = newSummary(up, down, mine)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reaction summaries: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"commentservice/internal/models"
+	"math"
+	"time"
+)
+
+// wilsonZ квантиль нормального распределения для доверия 95%
+const wilsonZ = 1.96
+
+// sortSpec порядок выборки: SQL выражение оценки (пусто для порядка по
+// времени создания) и направление. Равные ключи упорядочиваются по id в
+// том же направлении.
+type sortSpec struct {
+	score string
+	desc  bool
+}
+
+// sortSpecs порядки выборки. SQL функции оценок создаются миграцией 009.
+var sortSpecs = map[string]sortSpec{
+	models.SortOldest:        {},
+	models.SortNewest:        {desc: true},
+	models.SortTop:           {score: "(c.upvotes - c.downvotes)::float8", desc: true},
+	models.SortBest:          {score: "comment_wilson_score(c.upvotes, c.downvotes)", desc: true},
+	models.SortHot:           {score: "comment_hot_score(c.upvotes, c.downvotes, c.created_at)", desc: true},
+	models.SortControversial: {score: "comment_controversy_score(c.upvotes, c.downvotes)", desc: true},
+}
+
+// normalizeSort заменяет пустой порядок на порядок по умолчанию
+func normalizeSort(sort string) string {
+	if sort == "" {
+		return models.SortOldest
+	}
+	return sort
+}
+
+// commentScore вычисляет оценку комментария для порядка sort так же, как
+// SQL функции; для порядка по времени оценка не используется
+func commentScore(sort string, up, down int, createdAt time.Time) float64 {
+	switch sort {
+	case models.SortTop:
+		return float64(up - down)
+	case models.SortBest:
+		return wilsonScore(up, down)
+	case models.SortHot:
+		return hotScore(up, down, createdAt)
+	case models.SortControversial:
+		return controversyScore(up, down)
+	}
+	return 0
+}
+
+// wilsonScore нижняя граница доверительного интервала Вильсона для доли голосов "за"
+func wilsonScore(up, down int) float64 {
+	if up+down == 0 {
+		return 0
+	}
+	n := float64(up + down)
+	p := float64(up) / n
+	z := wilsonZ
+	return (p + z*z/(2*n) - z*math.Sqrt((p*(1-p)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
+
+// hotScore порядок величины счета плюс время создания в 12.5-часовых
+// интервалах: новый комментарий с тем же счетом выше старого
+func hotScore(up, down int, createdAt time.Time) float64 {
+	score := up - down
+	sign := 0.0
+	switch {
+	case score > 0:
+		sign = 1
+	case score < 0:
+		sign = -1
+	}
+	order := math.Log10(math.Max(math.Abs(float64(score)), 1))
+	return sign*order + float64(createdAt.UnixMicro())/1e6/45000
+}
+
+// controversyScore растет с числом голосов и их равновесием "за" и "против"
+func controversyScore(up, down int) float64 {
+	if up <= 0 || down <= 0 {
+		return 0
+	}
+	balance := float64(min(up, down)) / float64(max(up, down))
+	return math.Pow(float64(up+down), balance)
+}
@@ -0,0 +1,772 @@
+// Package storagetest содержит общие наборы проверок, которые должна
+// проходить любая реализация storage.CommentsStorage и storage.NewsStorage,
+// чтобы поведение Postgres-хранилища, хранилища в памяти и клиента
+// newsservice не расходилось.
+//
+// Использование в тестах реализации:
+//
+//	func TestMemoryStorage(t *testing.T) {
+//		storagetest.RunCommentsStorage(t, func(t *testing.T) storage.CommentsStorage {
+//			return storage.NewMemoryStorage(slog.Default())
+//		})
+//	}
+//
+// Для Postgres фабрика должна возвращать хранилище над пустой базой с
+// примененными миграциями. Проверки NewsStorage запускаются так же через
+// RunNewsStorage с фабрикой, создающей хранилище с заданными новостями.
+package storagetest
+
+import (
+	"commentservice/internal/apperr"
+	"commentservice/internal/models"
+	"commentservice/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// Factory создает пустое хранилище для одной проверки
+type Factory func(t *testing.T) storage.CommentsStorage
+
+// RunCommentsStorage запускает все проверки CommentsStorage. Если хранилище
+// реализует storage.OutboxStorage, проверяется и запись событий в outbox.
+func RunCommentsStorage(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.CommentsStorage)
+	}{
+		{"AddAndGet", testAddAndGet},
+		{"GetNotFound", testGetNotFound},
+		{"GetByIDs", testGetByIDs},
+		{"OffsetPagination", testOffsetPagination},
+		{"CursorPagination", testCursorPagination},
+		{"InvalidCursor", testInvalidCursor},
+		{"SortOrders", testSortOrders},
+		{"Thread", testThread},
+		{"UpdateWritesRevision", testUpdateWritesRevision},
+		{"SoftDelete", testSoftDelete},
+		{"NotFoundErrors", testNotFoundErrors},
+		{"ContentLength", testContentLength},
+		{"Reactions", testReactions},
+		{"Outbox", testOutbox},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(s.Close)
+			tt.fn(t, s)
+		})
+	}
+}
+
+// NewsFactory создает NewsStorage, в котором существуют ровно новости newsIDs.
+// Проверки всегда передают хотя бы одну новость.
+type NewsFactory func(t *testing.T, newsIDs ...int) storage.NewsStorage
+
+// RunNewsStorage запускает все проверки NewsStorage
+func RunNewsStorage(t *testing.T, newStorage NewsFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.NewsStorage)
+	}{
+		{"NewsExists", testNewsExists},
+		{"NewsExistsBatch", testNewsExistsBatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t, 1, 2)
+			t.Cleanup(s.Close)
+			tt.fn(t, s)
+		})
+	}
+}
+
+// mustAdd добавляет комментарий или прерывает проверку
+func mustAdd(t *testing.T, s storage.CommentsStorage, newsID int, content string, parentID *int) models.Comment {
+	t.Helper()
+
+	comment, err := s.AddComment(context.Background(), models.Comment{
+		NewsID:   newsID,
+		ParentID: parentID,
+		Content:  content,
+	})
+	if err != nil {
+		t.Fatalf("AddComment(%d, %q) error = %v", newsID, content, err)
+	}
+	return comment
+}
+
+// ids возвращает ID комментариев страницы
+func ids(comments []models.Comment) []int {
+	result := make([]int, 0, len(comments))
+	for _, c := range comments {
+		result = append(result, c.CommentID)
+	}
+	return result
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testAddAndGet(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	added, err := s.AddComment(ctx, models.Comment{
+		NewsID: 1, Content: "first", Cens: true, CensReason: "rule",
+		AuthorID: "user-1", AuthorName: "User One",
+	})
+	if err != nil {
+		t.Fatalf("AddComment() error = %v", err)
+	}
+	if added.CommentID < 1 {
+		t.Fatalf("AddComment() CommentID = %d, want positive", added.CommentID)
+	}
+	if added.CreatedAt.IsZero() {
+		t.Fatal("AddComment() CreatedAt is zero")
+	}
+
+	got, err := s.GetComment(ctx, added.CommentID)
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	if got.NewsID != 1 || got.Content != "first" || got.ParentID != nil || got.Deleted {
+		t.Errorf("GetComment() = %+v", got)
+	}
+	if !got.Cens || got.CensReason != "rule" {
+		t.Errorf("GetComment() Cens = %v, CensReason = %q, want true, %q", got.Cens, got.CensReason, "rule")
+	}
+	if got.AuthorID != "user-1" || got.AuthorName != "User One" {
+		t.Errorf("GetComment() AuthorID = %q, AuthorName = %q, want %q, %q", got.AuthorID, got.AuthorName, "user-1", "User One")
+	}
+	if !got.CreatedAt.Equal(added.CreatedAt) {
+		t.Errorf("GetComment() CreatedAt = %v, want %v", got.CreatedAt, added.CreatedAt)
+	}
+}
+
+func testGetNotFound(t *testing.T, s storage.CommentsStorage) {
+	_, err := s.GetComment(context.Background(), 1_000_000)
+	if !errors.Is(err, storage.ErrCommentNotFound) {
+		t.Errorf("GetComment() error = %v, want %v", err, storage.ErrCommentNotFound)
+	}
+}
+
+func testGetByIDs(t *testing.T, s storage.CommentsStorage) {
+	first := mustAdd(t, s, 1, "first", nil)
+	second := mustAdd(t, s, 2, "second", nil)
+
+	got, err := s.GetCommentsByIDs(context.Background(), []int{first.CommentID, 1_000_000, second.CommentID})
+	if err != nil {
+		t.Fatalf("GetCommentsByIDs() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetCommentsByIDs() returned %d comments, want 2", len(got))
+	}
+	if got[first.CommentID].Content != "first" || got[second.CommentID].NewsID != 2 {
+		t.Errorf("GetCommentsByIDs() = %+v", got)
+	}
+}
+
+func testOffsetPagination(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	var want []int
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		want = append(want, mustAdd(t, s, 2, content, nil).CommentID)
+	}
+	mustAdd(t, s, 3, "other news", nil)
+
+	page, err := s.GetComments(ctx, models.CommentQuery{NewsID: 2, Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("GetComments() error = %v", err)
+	}
+	if got := ids(page.Comments); !equalIDs(got, want[2:4]) {
+		t.Errorf("GetComments() ids = %v, want %v", got, want[2:4])
+	}
+	if page.Total != 5 {
+		t.Errorf("GetComments() Total = %d, want 5", page.Total)
+	}
+	if page.NextCursor == "" || page.PrevCursor == "" {
+		t.Errorf("GetComments() cursors = %q, %q, want both set", page.NextCursor, page.PrevCursor)
+	}
+
+	first, err := s.GetComments(ctx, models.CommentQuery{NewsID: 2, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetComments() error = %v", err)
+	}
+	if got := ids(first.Comments); !equalIDs(got, want) {
+		t.Errorf("GetComments() ids = %v, want %v", got, want)
+	}
+	if first.NextCursor != "" || first.PrevCursor != "" {
+		t.Errorf("GetComments() cursors = %q, %q, want none", first.NextCursor, first.PrevCursor)
+	}
+
+	empty, err := s.GetComments(ctx, models.CommentQuery{NewsID: 4, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetComments() error = %v", err)
+	}
+	if empty.Comments == nil || len(empty.Comments) != 0 || empty.Total != 0 {
+		t.Errorf("GetComments() for news without comments = %+v, want empty non-nil page", empty)
+	}
+}
+
+func testCursorPagination(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	var want []int
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		want = append(want, mustAdd(t, s, 5, content, nil).CommentID)
+	}
+
+	var got []int
+	query := models.CommentQuery{NewsID: 5, Limit: 2}
+	var last models.CommentPage
+	for range len(want) {
+		page, err := s.GetComments(ctx, query)
+		if err != nil {
+			t.Fatalf("GetComments() error = %v", err)
+		}
+		got = append(got, ids(page.Comments)...)
+		last = page
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if !equalIDs(got, want) {
+		t.Fatalf("forward pages ids = %v, want %v", got, want)
+	}
+
+	prev, err := s.GetComments(ctx, models.CommentQuery{NewsID: 5, Limit: 2, Cursor: last.PrevCursor})
+	if err != nil {
+		t.Fatalf("GetComments() error = %v", err)
+	}
+	if ids := ids(prev.Comments); !equalIDs(ids, want[2:4]) {
+		t.Errorf("previous page ids = %v, want %v", ids, want[2:4])
+	}
+	if prev.NextCursor == "" || prev.PrevCursor == "" {
+		t.Errorf("previous page cursors = %q, %q, want both set", prev.NextCursor, prev.PrevCursor)
+	}
+}
+
+func testInvalidCursor(t *testing.T, s storage.CommentsStorage) {
+	_, err := s.GetComments(context.Background(), models.CommentQuery{NewsID: 1, Limit: 10, Cursor: "not a cursor"})
+	if !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("GetComments() error = %v, want %v", err, storage.ErrInvalidCursor)
+	}
+}
+
+func testSortOrders(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	// votes[i] - голоса "за" и "против" i-го комментария
+	votes := [][2]int{{1, 0}, {5, 1}, {3, 3}, {0, 2}, {5, 1}, {10, 9}}
+	var added []int
+	for i, v := range votes {
+		comment := mustAdd(t, s, 11, "sorted", nil)
+		added = append(added, comment.CommentID)
+		for j := range v[0] {
+			mustReact(t, s, comment.CommentID, fmt.Sprintf("up-%d-%d", i, j), models.ReactionUp)
+		}
+		for j := range v[1] {
+			mustReact(t, s, comment.CommentID, fmt.Sprintf("down-%d-%d", i, j), models.ReactionDown)
+		}
+	}
+
+	tests := []struct {
+		sort string
+		want []int
+	}{
+		{models.SortOldest, []int{0, 1, 2, 3, 4, 5}},
+		{models.SortNewest, []int{5, 4, 3, 2, 1, 0}},
+		{models.SortTop, []int{4, 1, 5, 0, 2, 3}},
+		{models.SortControversial, []int{5, 2, 4, 1, 3, 0}},
+	}
+	for _, tt := range tests {
+		want := make([]int, 0, len(tt.want))
+		for _, i := range tt.want {
+			want = append(want, added[i])
+		}
+
+		page, err := s.GetComments(ctx, models.CommentQuery{NewsID: 11, Limit: 10, Sort: tt.sort})
+		if err != nil {
+			t.Fatalf("GetComments(%s) error = %v", tt.sort, err)
+		}
+		if got := ids(page.Comments); !equalIDs(got, want) {
+			t.Errorf("GetComments(%s) ids = %v, want %v", tt.sort, got, want)
+		}
+	}
+
+	// Курсорная и постраничная выборки совпадают для каждого порядка
+	for _, sort := range []string{models.SortNewest, models.SortTop, models.SortBest, models.SortHot, models.SortControversial} {
+		all, err := s.GetComments(ctx, models.CommentQuery{NewsID: 11, Limit: 10, Sort: sort})
+		if err != nil {
+			t.Fatalf("GetComments(%s) error = %v", sort, err)
+		}
+
+		var got []int
+		query := models.CommentQuery{NewsID: 11, Limit: 4, Sort: sort}
+		var last models.CommentPage
+		for range len(votes) {
+			page, err := s.GetComments(ctx, query)
+			if err != nil {
+				t.Fatalf("GetComments(%s) error = %v", sort, err)
+			}
+			got = append(got, ids(page.Comments)...)
+			last = page
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		if want := ids(all.Comments); !equalIDs(got, want) {
+			t.Errorf("GetComments(%s) cursor pages ids = %v, want %v", sort, got, want)
+		}
+
+		prev, err := s.GetComments(ctx, models.CommentQuery{NewsID: 11, Limit: 4, Sort: sort, Cursor: last.PrevCursor})
+		if err != nil {
+			t.Fatalf("GetComments(%s) previous page error = %v", sort, err)
+		}
+		if got, want := ids(prev.Comments), ids(all.Comments)[:4]; !equalIDs(got, want) {
+			t.Errorf("GetComments(%s) previous page ids = %v, want %v", sort, got, want)
+		}
+
+		_, err = s.GetComments(ctx, models.CommentQuery{NewsID: 11, Limit: 4, Cursor: last.PrevCursor})
+		if !errors.Is(err, storage.ErrInvalidCursor) {
+			t.Errorf("GetComments() with %s cursor error = %v, want %v", sort, err, storage.ErrInvalidCursor)
+		}
+	}
+}
+
+// mustReact ставит реакцию или прерывает проверку
+func mustReact(t *testing.T, s storage.CommentsStorage, commentID int, userID, reaction string) {
+	t.Helper()
+
+	if _, err := s.SetReaction(context.Background(), commentID, userID, reaction); err != nil {
+		t.Fatalf("SetReaction(%d, %s, %s) error = %v", commentID, userID, reaction, err)
+	}
+}
+
+func testThread(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	root := mustAdd(t, s, 6, "root", nil)
+	reply := mustAdd(t, s, 6, "reply", &root.CommentID)
+	nested := mustAdd(t, s, 6, "nested", &reply.CommentID)
+	mustAdd(t, s, 6, "deep", &nested.CommentID)
+	second := mustAdd(t, s, 6, "second root", nil)
+
+	type node struct{ id, depth, replies int }
+	tests := []struct {
+		name  string
+		query models.ThreadQuery
+		want  []node
+	}{
+		{
+			name:  "all roots",
+			query: models.ThreadQuery{NewsID: 6, MaxDepth: 1},
+			want: []node{
+				{root.CommentID, 0, 1},
+				{second.CommentID, 0, 0},
+				{reply.CommentID, 1, 1},
+			},
+		},
+		{
+			name:  "first root",
+			query: models.ThreadQuery{NewsID: 6, MaxDepth: 1, Limit: 1},
+			want: []node{
+				{root.CommentID, 0, 1},
+				{reply.CommentID, 1, 1},
+			},
+		},
+		{
+			name:  "second root",
+			query: models.ThreadQuery{NewsID: 6, MaxDepth: 1, Limit: 1, Offset: 1},
+			want:  []node{{second.CommentID, 0, 0}},
+		},
+		{
+			name:  "past the end",
+			query: models.ThreadQuery{NewsID: 6, MaxDepth: 1, Offset: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		nodes, total, err := s.GetCommentThread(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("%s: GetCommentThread() error = %v", tt.name, err)
+		}
+		if total != 2 {
+			t.Errorf("%s: GetCommentThread() total = %d, want 2", tt.name, total)
+		}
+		if len(nodes) != len(tt.want) {
+			t.Fatalf("%s: GetCommentThread() returned %d nodes, want %d", tt.name, len(nodes), len(tt.want))
+		}
+		for i, w := range tt.want {
+			got := node{nodes[i].CommentID, nodes[i].Depth, nodes[i].ReplyCount}
+			if got != w {
+				t.Errorf("%s: node %d = %+v, want %+v", tt.name, i, got, w)
+			}
+		}
+	}
+}
+
+func testUpdateWritesRevision(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	comment := mustAdd(t, s, 7, "v1", nil)
+	for _, content := range []string{"v2", "v3"} {
+		comment.Content = content
+		updated, _, err := s.UpdateComment(ctx, comment)
+		if err != nil {
+			t.Fatalf("UpdateComment() error = %v", err)
+		}
+		if updated.Content != content {
+			t.Errorf("UpdateComment() Content = %q, want %q", updated.Content, content)
+		}
+	}
+
+	revisions, err := s.GetCommentRevisions(ctx, comment.CommentID)
+	if err != nil {
+		t.Fatalf("GetCommentRevisions() error = %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Content != "v1" || revisions[1].Content != "v2" {
+		t.Errorf("GetCommentRevisions() = %+v, want contents v1, v2", revisions)
+	}
+
+	fresh := mustAdd(t, s, 7, "no edits", nil)
+	revisions, err = s.GetCommentRevisions(ctx, fresh.CommentID)
+	if err != nil {
+		t.Fatalf("GetCommentRevisions() error = %v", err)
+	}
+	if revisions == nil || len(revisions) != 0 {
+		t.Errorf("GetCommentRevisions() = %v, want empty non-nil slice", revisions)
+	}
+
+	histories, err := s.GetCommentRevisionsByIDs(ctx, []int{comment.CommentID, fresh.CommentID, 1_000_000})
+	if err != nil {
+		t.Fatalf("GetCommentRevisionsByIDs() error = %v", err)
+	}
+	if len(histories) != 2 {
+		t.Fatalf("GetCommentRevisionsByIDs() returned %d comments, want 2", len(histories))
+	}
+	if got := histories[comment.CommentID]; len(got) != 2 || got[0].Content != "v1" || got[1].Content != "v2" {
+		t.Errorf("GetCommentRevisionsByIDs()[%d] = %+v, want contents v1, v2", comment.CommentID, got)
+	}
+	if got := histories[fresh.CommentID]; got == nil || len(got) != 0 {
+		t.Errorf("GetCommentRevisionsByIDs()[%d] = %v, want empty non-nil slice", fresh.CommentID, got)
+	}
+}
+
+func testSoftDelete(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+
+	parent := mustAdd(t, s, 8, "parent", nil)
+	child := mustAdd(t, s, 8, "child", &parent.CommentID)
+
+	if deleted, err := s.DeleteComment(ctx, parent.CommentID); err != nil || !deleted {
+		t.Fatalf("DeleteComment() = %v, %v, want true, nil", deleted, err)
+	}
+	if deleted, err := s.DeleteComment(ctx, parent.CommentID); err != nil || deleted {
+		t.Fatalf("repeated DeleteComment() = %v, %v, want false, nil", deleted, err)
+	}
+
+	got, err := s.GetComment(ctx, parent.CommentID)
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	if !got.Deleted || got.Content != "" {
+		t.Errorf("deleted comment = %+v, want tombstone", got)
+	}
+
+	nodes, _, err := s.GetCommentThread(ctx, models.ThreadQuery{NewsID: 8, MaxDepth: 5})
+	if err != nil {
+		t.Fatalf("GetCommentThread() error = %v", err)
+	}
+	if len(nodes) != 2 || nodes[1].CommentID != child.CommentID {
+		t.Errorf("GetCommentThread() after delete = %+v, want tombstone with its reply", nodes)
+	}
+
+	// История удаленного комментария скрыта так же, как его текст
+	edited := mustAdd(t, s, 8, "secret", nil)
+	edited.Content = "public"
+	if _, _, err := s.UpdateComment(ctx, edited); err != nil {
+		t.Fatalf("UpdateComment() error = %v", err)
+	}
+	if _, err := s.DeleteComment(ctx, edited.CommentID); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+	revisions, err := s.GetCommentRevisions(ctx, edited.CommentID)
+	if err != nil || revisions == nil || len(revisions) != 0 {
+		t.Errorf("GetCommentRevisions() of deleted = %v, %v, want empty non-nil slice", revisions, err)
+	}
+	histories, err := s.GetCommentRevisionsByIDs(ctx, []int{edited.CommentID})
+	if err != nil {
+		t.Fatalf("GetCommentRevisionsByIDs() error = %v", err)
+	}
+	if got, ok := histories[edited.CommentID]; !ok || got == nil || len(got) != 0 {
+		t.Errorf("GetCommentRevisionsByIDs() of deleted = %v, want empty non-nil slice", histories)
+	}
+
+	parent.Content = "edited"
+	if _, _, err := s.UpdateComment(ctx, parent); !errors.Is(err, storage.ErrCommentDeleted) {
+		t.Errorf("UpdateComment() on deleted error = %v, want %v", err, storage.ErrCommentDeleted)
+	}
+}
+
+func testReactions(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+	comment := mustAdd(t, s, 10, "voted", nil)
+	other := mustAdd(t, s, 10, "not voted", nil)
+
+	steps := []struct {
+		user     string
+		reaction string
+		want     models.ReactionSummary
+	}{
+		{"alice", models.ReactionUp, models.ReactionSummary{Up: 1, Score: 1, Mine: models.ReactionUp}},
+		{"alice", models.ReactionUp, models.ReactionSummary{Up: 1, Score: 1, Mine: models.ReactionUp}},
+		{"bob", models.ReactionDown, models.ReactionSummary{Up: 1, Down: 1, Mine: models.ReactionDown}},
+		{"alice", models.ReactionDown, models.ReactionSummary{Down: 2, Score: -2, Mine: models.ReactionDown}},
+	}
+	for _, step := range steps {
+		got, err := s.SetReaction(ctx, comment.CommentID, step.user, step.reaction)
+		if err != nil {
+			t.Fatalf("SetReaction(%s, %s) error = %v", step.user, step.reaction, err)
+		}
+		if got != step.want {
+			t.Errorf("SetReaction(%s, %s) = %+v, want %+v", step.user, step.reaction, got, step.want)
+		}
+	}
+
+	got, err := s.RemoveReaction(ctx, comment.CommentID, "bob")
+	if err != nil {
+		t.Fatalf("RemoveReaction() error = %v", err)
+	}
+	if want := (models.ReactionSummary{Down: 1, Score: -1}); got != want {
+		t.Errorf("RemoveReaction() = %+v, want %+v", got, want)
+	}
+	if _, err := s.RemoveReaction(ctx, comment.CommentID, "bob"); err != nil {
+		t.Errorf("repeated RemoveReaction() error = %v", err)
+	}
+
+	summaries, err := s.GetReactionSummaries(ctx, []int{comment.CommentID, other.CommentID, 1_000_000}, "alice")
+	if err != nil {
+		t.Fatalf("GetReactionSummaries() error = %v", err)
+	}
+	want := map[int]models.ReactionSummary{
+		comment.CommentID: {Down: 1, Score: -1, Mine: models.ReactionDown},
+		other.CommentID:   {},
+	}
+	if len(summaries) != len(want) || summaries[comment.CommentID] != want[comment.CommentID] || summaries[other.CommentID] != want[other.CommentID] {
+		t.Errorf("GetReactionSummaries() = %+v, want %+v", summaries, want)
+	}
+
+	if _, err := s.SetReaction(ctx, 1_000_000, "alice", models.ReactionUp); !errors.Is(err, storage.ErrCommentNotFound) {
+		t.Errorf("SetReaction() on missing error = %v, want %v", err, storage.ErrCommentNotFound)
+	}
+	if _, err := s.DeleteComment(ctx, other.CommentID); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+	if _, err := s.SetReaction(ctx, other.CommentID, "alice", models.ReactionUp); !errors.Is(err, storage.ErrCommentDeleted) {
+		t.Errorf("SetReaction() on deleted error = %v, want %v", err, storage.ErrCommentDeleted)
+	}
+}
+
+func testContentLength(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+	// Длина считается в символах, а не байтах
+	longest := strings.Repeat("ж", storage.MaxContentLength)
+
+	comment := mustAdd(t, s, 11, longest, nil)
+	if comment.Content != longest {
+		t.Errorf("AddComment() stored %d characters, want %d", utf8.RuneCountInString(comment.Content), storage.MaxContentLength)
+	}
+	comment.Content = longest
+	if _, _, err := s.UpdateComment(ctx, comment); err != nil {
+		t.Errorf("UpdateComment() with %d characters error = %v", storage.MaxContentLength, err)
+	}
+
+	tooLong := longest + "ж"
+	if _, err := s.AddComment(ctx, models.Comment{NewsID: 11, Content: tooLong}); !errors.Is(err, apperr.ErrValidation) {
+		t.Errorf("AddComment() with %d characters error = %v, want %v", storage.MaxContentLength+1, err, apperr.ErrValidation)
+	}
+	comment.Content = tooLong
+	if _, _, err := s.UpdateComment(ctx, comment); !errors.Is(err, apperr.ErrValidation) {
+		t.Errorf("UpdateComment() with %d characters error = %v, want %v", storage.MaxContentLength+1, err, apperr.ErrValidation)
+	}
+}
+
+func testNotFoundErrors(t *testing.T, s storage.CommentsStorage) {
+	ctx := context.Background()
+	const missing = 1_000_000
+
+	if _, _, err := s.UpdateComment(ctx, models.Comment{CommentID: missing, Content: "x"}); !errors.Is(err, storage.ErrCommentNotFound) {
+		t.Errorf("UpdateComment() error = %v, want %v", err, storage.ErrCommentNotFound)
+	}
+	if _, err := s.DeleteComment(ctx, missing); !errors.Is(err, storage.ErrCommentNotFound) {
+		t.Errorf("DeleteComment() error = %v, want %v", err, storage.ErrCommentNotFound)
+	}
+	if _, err := s.GetCommentRevisions(ctx, missing); !errors.Is(err, storage.ErrCommentNotFound) {
+		t.Errorf("GetCommentRevisions() error = %v, want %v", err, storage.ErrCommentNotFound)
+	}
+}
+
+func testOutbox(t *testing.T, s storage.CommentsStorage) {
+	outbox, ok := s.(storage.OutboxStorage)
+	if !ok {
+		t.Skip("storage does not implement OutboxStorage")
+	}
+	ctx := context.Background()
+
+	comment := mustAdd(t, s, 9, "text", nil)
+	comment.Content = "edited"
+	comment.Cens = true
+	if _, wasCens, err := s.UpdateComment(ctx, comment); err != nil || wasCens {
+		t.Fatalf("UpdateComment() wasCens = %v, error = %v, want false, nil", wasCens, err)
+	}
+	// Повторная правка уже отмеченного комментария не порождает comment.censored
+	comment.Content = "edited again"
+	if _, wasCens, err := s.UpdateComment(ctx, comment); err != nil || !wasCens {
+		t.Fatalf("UpdateComment() wasCens = %v, error = %v, want true, nil", wasCens, err)
+	}
+	for range 2 {
+		if _, err := s.DeleteComment(ctx, comment.CommentID); err != nil {
+			t.Fatalf("DeleteComment() error = %v", err)
+		}
+	}
+
+	wantTypes := []string{
+		models.EventCommentCreated,
+		models.EventCommentUpdated,
+		models.EventCommentCensored,
+		models.EventCommentUpdated,
+		models.EventCommentDeleted,
+	}
+
+	// Первый проход ничего не публикует: события должны остаться в outbox
+	var seen []models.OutboxEvent
+	_, err := outbox.RelayOutbox(ctx, 100, func(_ context.Context, events []models.OutboxEvent) []int64 {
+		seen = events
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RelayOutbox() error = %v", err)
+	}
+	if len(seen) != len(wantTypes) {
+		t.Fatalf("RelayOutbox() passed %d events, want %d", len(seen), len(wantTypes))
+	}
+	for i, event := range seen {
+		if event.Type != wantTypes[i] || event.NewsID != 9 {
+			t.Errorf("event %d = %s for news %d, want %s for news 9", i, event.Type, event.NewsID, wantTypes[i])
+		}
+		var decoded models.CommentEvent
+		if err := json.Unmarshal(event.Payload, &decoded); err != nil || decoded.CommentID != comment.CommentID {
+			t.Errorf("event %d payload = %s, error = %v", i, event.Payload, err)
+		}
+	}
+
+	// Второй проход публикует все, третий уже ничего не находит
+	_, err = outbox.RelayOutbox(ctx, 100, func(_ context.Context, events []models.OutboxEvent) []int64 {
+		published := make([]int64, 0, len(events))
+		for _, event := range events {
+			published = append(published, event.ID)
+		}
+		return published
+	})
+	if err != nil {
+		t.Fatalf("RelayOutbox() error = %v", err)
+	}
+	n, err := outbox.RelayOutbox(ctx, 100, func(context.Context, []models.OutboxEvent) []int64 {
+		t.Error("RelayOutbox() passed already published events")
+		return nil
+	})
+	if err != nil || n != 0 {
+		t.Errorf("RelayOutbox() = %d, %v, want 0, nil", n, err)
+	}
+
+	// Неопубликованное событие переживает очистку, опубликованные удаляются
+	mustAdd(t, s, 9, "unpublished", nil)
+	if n, err := outbox.PruneOutbox(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("PruneOutbox() before retention = %d, %v, want 0, nil", n, err)
+	}
+	if n, err := outbox.PruneOutbox(ctx, time.Now().Add(time.Hour)); err != nil || n != len(wantTypes) {
+		t.Errorf("PruneOutbox() = %d, %v, want %d, nil", n, err, len(wantTypes))
+	}
+	n, err = outbox.RelayOutbox(ctx, 100, func(_ context.Context, events []models.OutboxEvent) []int64 {
+		if len(events) != 1 || events[0].Type != models.EventCommentCreated {
+			t.Errorf("RelayOutbox() after prune passed %+v, want the unpublished event", events)
+		}
+		return nil
+	})
+	if err != nil || n != 1 {
+		t.Errorf("RelayOutbox() after prune = %d, %v, want 1, nil", n, err)
+	}
+}
+
+func testNewsExists(t *testing.T, s storage.NewsStorage) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		newsID int
+		want   bool
+	}{
+		{1, true},
+		{2, true},
+		{3, false},
+	} {
+		got, err := s.NewsExists(ctx, tt.newsID)
+		if err != nil {
+			t.Fatalf("NewsExists(%d) error = %v", tt.newsID, err)
+		}
+		if got != tt.want {
+			t.Errorf("NewsExists(%d) = %v, want %v", tt.newsID, got, tt.want)
+		}
+	}
+
+	for _, newsID := range []int{0, -1} {
+		if _, err := s.NewsExists(ctx, newsID); !errors.Is(err, apperr.ErrValidation) {
+			t.Errorf("NewsExists(%d) error = %v, want %v", newsID, err, apperr.ErrValidation)
+		}
+	}
+}
+
+func testNewsExistsBatch(t *testing.T, s storage.NewsStorage) {
+	ctx := context.Background()
+
+	got, err := s.NewsExistsBatch(ctx, []int{1, 3, 2, 1, 0, -1})
+	if err != nil {
+		t.Fatalf("NewsExistsBatch() error = %v", err)
+	}
+	want := map[int]bool{1: true, 2: true, 3: false, 0: false, -1: false}
+	if len(got) != len(want) {
+		t.Errorf("NewsExistsBatch() = %v, want %v", got, want)
+	}
+	for id, exists := range want {
+		if v, ok := got[id]; !ok || v != exists {
+			t.Errorf("NewsExistsBatch()[%d] = %v, %v, want %v, true", id, v, ok, exists)
+		}
+	}
+
+	empty, err := s.NewsExistsBatch(ctx, nil)
+	if err != nil {
+		t.Fatalf("NewsExistsBatch(nil) error = %v", err)
+	}
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("NewsExistsBatch(nil) = %v, want empty non-nil map", empty)
+	}
+}
@@ -0,0 +1,113 @@
+package storagetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NewsServer заглушка HTTP API newsservice: GET /news/{id} отвечает 200 для
+// известных новостей и 404 для остальных. Считает полученные запросы, чтобы
+// тесты могли проверить работу кэша.
+type NewsServer struct {
+	*httptest.Server
+
+	mu       sync.RWMutex
+	news     map[int]bool
+	status   int
+	hold     *hold
+	requests atomic.Int64
+}
+
+// hold задерживает ответы сервера до release
+type hold struct {
+	arrived     chan struct{}
+	arrivedOnce sync.Once
+	release     chan struct{}
+}
+
+// NewNewsServer запускает заглушку newsservice на локальном порту.
+// После использования сервер нужно закрыть.
+func NewNewsServer(newsIDs ...int) *NewsServer {
+	s := &NewsServer{news: make(map[int]bool)}
+	s.AddNews(newsIDs...)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddNews добавляет новости
+func (s *NewsServer) AddNews(newsIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range newsIDs {
+		s.news[id] = true
+	}
+}
+
+// Fail заставляет сервер отвечать на все запросы кодом status.
+// Fail(0) возвращает обычное поведение.
+func (s *NewsServer) Fail(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// Block задерживает ответы на запросы до вызова release. Канал arrived
+// закрывается, когда сервер получает первый задержанный запрос.
+func (s *NewsServer) Block() (arrived <-chan struct{}, release func()) {
+	h := &hold{arrived: make(chan struct{}), release: make(chan struct{})}
+	s.mu.Lock()
+	s.hold = h
+	s.mu.Unlock()
+
+	var once sync.Once
+	return h.arrived, func() {
+		once.Do(func() { close(h.release) })
+	}
+}
+
+// Requests возвращает число обработанных запросов
+func (s *NewsServer) Requests() int64 {
+	return s.requests.Load()
+}
+
+func (s *NewsServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+
+	s.mu.RLock()
+	status, h := s.status, s.hold
+	s.mu.RUnlock()
+	if h != nil {
+		h.arrivedOnce.Do(func() { close(h.arrived) })
+		select {
+		case <-h.release:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	idStr, ok := strings.CutPrefix(r.URL.Path, "/news/")
+	id, err := strconv.Atoi(idStr)
+	if r.Method != http.MethodGet || !ok || err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	exists := s.news[id]
+	s.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"id":` + idStr + `}`))
+}
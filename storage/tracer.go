@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"commentservice/internal/tracing"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer создает спан на каждый запрос к базе
+type queryTracer struct {
+	dbName string
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracing.Tracer().Start(ctx, "postgres "+queryOperation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBNamespace(t.dbName),
+			semconv.DBQueryText(data.SQL),
+		))
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	// отсутствие строк - ожидаемый результат, а не сбой запроса
+	if !errors.Is(data.Err, pgx.ErrNoRows) {
+		tracing.RecordError(span, data.Err)
+	}
+	span.End()
+}
+
+// queryOperation возвращает первое ключевое слово запроса: SELECT, INSERT и т.п.
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}